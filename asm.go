@@ -0,0 +1,299 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// hackPath returns the path of the file assembled from the asm file.
+// The .asm extension of the file name is replaced with ext, or ext is appended
+// if the file name has another extension.
+// The file is put in dir if it is not empty, or next to the asm file otherwise.
+// Assembling from the standard input writes to the standard output unless dir is given.
+func hackPath(asmFile string, dir string, ext string) string {
+	if asmFile == stdio {
+		if dir == "" {
+			return stdio
+		}
+		asmFile = "stdin.asm"
+	}
+
+	name := filepath.Base(asmFile)
+	if filepath.Ext(name) == ".asm" {
+		name = strings.TrimSuffix(name, ".asm")
+	}
+	name += ext
+
+	if dir == "" {
+		dir = filepath.Dir(asmFile)
+	}
+
+	return filepath.Join(dir, name)
+}
+
+const outDirMode = 0o755
+
+// asmJob is the assembly of one asm file into one hack file.
+type asmJob struct {
+	asmFile  string
+	hackFile string
+
+	// Results, set by run.
+	words       int
+	err         error
+	diagnostics bytes.Buffer
+	done        chan struct{}
+}
+
+// run assembles the asm file and writes its warnings and errors to the diagnostics.
+func (j *asmJob) run(config assemblerConfig, keepPartial bool) {
+	defer close(j.done)
+
+	reader, err := openInput(j.asmFile)
+	if err != nil {
+		j.err = err
+		fmt.Fprintf(&j.diagnostics, "Error: could not open asm file: %s\n", err.Error())
+		return
+	}
+	defer reader.Close()
+
+	writer, err := createOutput(j.hackFile)
+	if err != nil {
+		j.err = err
+		fmt.Fprintf(&j.diagnostics, "Error: could not create hack file: %s\n", err.Error())
+		return
+	}
+
+	assembler := config.newAssembler(reader, writer)
+
+	err = config.assemble(assembler)
+	fprintWarnings(&j.diagnostics, assembler)
+	if err != nil {
+		j.err = err
+		fprintErrors(&j.diagnostics, "could not assemble file: ", err)
+		if partial := writer.Abort(keepPartial); partial != "" {
+			fmt.Fprintf(&j.diagnostics, "Partial hack file kept: %s\n", partial)
+		}
+		return
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		j.err = err
+		fmt.Fprintf(&j.diagnostics, "Error: could not write hack file: %s\n", err.Error())
+		return
+	}
+
+	j.words = assembler.Size()
+	if stripped := assembler.Stripped(); stripped > 0 {
+		fmt.Fprintf(&j.diagnostics, "Stripped %d words of unreachable routines\n", stripped)
+	}
+}
+
+// runJobs runs the jobs on the given number of workers.
+// It returns immediately; wait for the done channel of each job for its results.
+func runJobs(jobs []*asmJob, workers int, config assemblerConfig, keepPartial bool) {
+	queue := make(chan *asmJob)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range queue {
+				job.run(config, keepPartial)
+			}
+		}()
+	}
+
+	go func() {
+		for _, job := range jobs {
+			queue <- job
+		}
+		close(queue)
+	}()
+}
+
+// asmRun is an asm command with its options parsed.
+type asmRun struct {
+	outFile     string
+	outDir      string
+	ext         string
+	keepPartial bool
+	workers     int
+	config      assemblerConfig
+
+	// batch is true if several asm files, a directory or a pattern are assembled.
+	batch bool
+}
+
+func runAsm(args []string) int {
+	flagSet := newFlagSet("asm", "[options] <asm file | directory | pattern>...\n"+
+		"Use - as the asm file to read from the standard input and write to the standard output.\n"+
+		"Directories are searched recursively for asm files, and patterns may use ** to match any directories.")
+	outFile := flagSet.String("o", "", "output file, or - for the standard output")
+	outDir := flagSet.String("d", "", "output directory")
+	keepPartial := flagSet.Bool("keep-partial", false, "keep the partially written hack file when assembly fails")
+	workers := flagSet.Int("j", runtime.NumCPU(), "number of files assembled in parallel")
+	watch := flagSet.Bool("watch", false, "reassemble whenever an asm file changes, until interrupted")
+	object := flagSet.Bool("c", false,
+		"assemble each asm file into a relocatable .o object to be linked by the link command")
+	options := newAssemblerFlags(flagSet)
+
+	// Options may follow the asm files, as in asm prog.asm -o prog.hack.
+	inputs, ok, code := parseInterspersedFlags(flagSet, args)
+	if !ok {
+		return code
+	}
+
+	if len(inputs) == 0 || *workers < 1 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	if *outFile != "" && *outDir != "" {
+		fmt.Fprintln(os.Stderr, "Error: -o and -d can not be used together")
+		return exitUsage
+	}
+
+	r := &asmRun{outFile: *outFile, outDir: *outDir, ext: ".hack", keepPartial: *keepPartial, workers: *workers}
+	if *object {
+		r.ext = ".o"
+	}
+
+	jobs, ok, code := r.newJobs(inputs)
+	if !ok {
+		return code
+	}
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+	config.object = *object
+	r.config = config
+
+	if *outDir != "" {
+		err = os.MkdirAll(*outDir, outDirMode)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not create output directory: %s\n", err.Error())
+			return exitFailure
+		}
+	}
+
+	if *watch {
+		return r.watch(inputs)
+	}
+
+	return r.run(jobs)
+}
+
+// newJobs returns the jobs assembling the asm files named by the inputs.
+// It returns false and the exit code if they can not be assembled.
+func (r *asmRun) newJobs(inputs []string) ([]*asmJob, bool, int) {
+	asmFiles, err := expandInputs(inputs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return nil, false, exitFailure
+	}
+
+	r.batch = len(asmFiles) != 1 || len(inputs) != 1 || hasMeta(inputs[0]) || isDir(inputs[0])
+	if r.batch && r.outFile != "" {
+		fmt.Fprintln(os.Stderr, "Error: -o can not be used with several asm files")
+		return nil, false, exitUsage
+	}
+
+	jobs, err := newAsmJobs(asmFiles, r.outFile, r.outDir, r.ext)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return nil, false, exitUsage
+	}
+
+	return jobs, true, exitOK
+}
+
+// run assembles the jobs once and returns the exit code.
+func (r *asmRun) run(jobs []*asmJob) int {
+	runJobs(jobs, r.workers, r.config, r.keepPartial)
+
+	if printJobs(jobs, r.batch, r.config.config.MemoryMap().ROMSize) > 0 {
+		return exitFailure
+	}
+	return exitOK
+}
+
+// watch reassembles the asm files named by the inputs whenever they change, until interrupted.
+func (r *asmRun) watch(inputs []string) int {
+	return watchAsm(inputs, func(asmFiles []string) {
+		jobs, err := newAsmJobs(asmFiles, r.outFile, r.outDir, r.ext)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+			return
+		}
+		runJobs(jobs, r.workers, r.config, r.keepPartial)
+		printJobs(jobs, true, r.config.config.MemoryMap().ROMSize)
+	})
+}
+
+// printJobs waits for the jobs and prints their diagnostics in the order of the asm files.
+// In batch mode, the diagnostics are prefixed with the asm file,
+// and a summary line is printed for each job and for the whole batch.
+// It returns the number of failed jobs.
+func printJobs(jobs []*asmJob, batch bool, romSize uint) int {
+	failed := 0
+	for _, job := range jobs {
+		<-job.done
+
+		if job.err != nil {
+			failed++
+		}
+		if !batch {
+			os.Stderr.Write(job.diagnostics.Bytes())
+			continue
+		}
+
+		// Each job is printed at once, in the order of the asm files.
+		for _, line := range strings.SplitAfter(job.diagnostics.String(), "\n") {
+			if line != "" {
+				fmt.Fprintf(os.Stderr, "%s: %s", job.asmFile, line)
+			}
+		}
+		if job.err != nil {
+			fmt.Printf("FAIL %s\n", job.asmFile)
+		} else {
+			fmt.Printf("ok   %s -> %s (%d words, %.1f%% of ROM)\n",
+				job.asmFile, job.hackFile, job.words, float64(job.words)*100/float64(romSize))
+		}
+	}
+
+	if batch {
+		fmt.Printf("%d files assembled, %d failed\n", len(jobs)-failed, failed)
+	}
+
+	return failed
+}
+
+// newAsmJobs returns the jobs assembling the asm files into files with the extension ext.
+// It returns an error if two asm files would be assembled into the same file.
+func newAsmJobs(asmFiles []string, outFile string, outDir string, ext string) ([]*asmJob, error) {
+	jobs := make([]*asmJob, 0, len(asmFiles))
+	sources := make(map[string]string, len(asmFiles))
+
+	for _, asmFile := range asmFiles {
+		hackFile := outFile
+		if hackFile == "" {
+			hackFile = hackPath(asmFile, outDir, ext)
+		}
+
+		if source, ok := sources[hackFile]; ok && hackFile != stdio {
+			return nil, fmt.Errorf("%s and %s are both assembled into %s", source, asmFile, hackFile)
+		}
+		sources[hackFile] = asmFile
+
+		jobs = append(jobs, &asmJob{asmFile: asmFile, hackFile: hackFile, done: make(chan struct{})})
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestHackPath(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asmFile  string
+		dir      string
+		ext      string
+		hackFile string
+	}{
+		{"next to the asm file", "src/prog.asm", "", ".hack", filepath.Join("src", "prog.hack")},
+		{"asm in a directory name", "x.asm.d/prog.asm", "", ".hack", filepath.Join("x.asm.d", "prog.hack")},
+		{"other extension", "prog.s", "", ".hack", "prog.s.hack"},
+		{"no extension", "prog", "", ".hack", "prog.hack"},
+		{"output directory", "src/prog.asm", "out", ".hack", filepath.Join("out", "prog.hack")},
+		{"object", "src/prog.asm", "", ".o", filepath.Join("src", "prog.o")},
+		{"standard input", stdio, "", ".hack", stdio},
+		{"standard input to a directory", stdio, "out", ".hack", filepath.Join("out", "stdin.hack")},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			if hackFile := hackPath(d.asmFile, d.dir, d.ext); hackFile != d.hackFile {
+				t.Errorf("expected %s, got %s", d.hackFile, hackFile)
+			}
+		})
+	}
+}
+
+func TestNewAsmJobs(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asmFiles []string
+		outFile  string
+		outDir   string
+		ok       bool
+	}{
+		{"different directories", []string{"a/prog.asm", "b/prog.asm"}, "", "", true},
+		{"same output directory", []string{"a/prog.asm", "b/prog.asm"}, "", "out", false},
+		{"same hack file", []string{"prog.asm", "prog"}, "", "", false},
+		{"same output file", []string{"a.asm", "b.asm"}, "prog.hack", "", false},
+		{"standard output", []string{stdio, "a.asm"}, stdio, "", true},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			jobs, err := newAsmJobs(d.asmFiles, d.outFile, d.outDir, ".hack")
+			if d.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !d.ok {
+				if err == nil {
+					t.Errorf("expected a collision, got %d jobs", len(jobs))
+				}
+				return
+			}
+			if len(jobs) != len(d.asmFiles) {
+				t.Errorf("expected %d jobs, got %d", len(d.asmFiles), len(jobs))
+			}
+		})
+	}
+}
+
+func TestRunAsm_ExitCode(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "a/prog.asm", "b/prog.asm", "c/main.asm")
+
+	path := func(file string) string {
+		return filepath.Join(dir, filepath.FromSlash(file))
+	}
+
+	data := []struct {
+		testCase string
+		args     []string
+		code     int
+	}{
+		{"no asm file", nil, exitUsage},
+		{"unknown flag", []string{"-unknown", path("c/main.asm")}, exitUsage},
+		{"output file and directory", []string{"-o", path("c/x.hack"), "-d", path("out1"), path("c/main.asm")}, exitUsage},
+		{"output file after the asm file", []string{path("c/main.asm"), "-o", path("c/x.hack")}, exitOK},
+		{"output file with several asm files", []string{"-o", path("c/x.hack"), path("a"), path("b")}, exitUsage},
+		{"collision", []string{"-d", path("out2"), path("**/prog.asm")}, exitUsage},
+		{"no match", []string{path("**/*.s")}, exitFailure},
+		{"missing asm file", []string{path("missing.asm")}, exitFailure},
+		{"batch", []string{"-d", path("out3"), path("**/main.asm")}, exitOK},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			if code := runAsm(d.args); code != d.code {
+				t.Errorf("expected exit code %d, got %d", d.code, code)
+			}
+		})
+	}
+}
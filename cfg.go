@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func runCFG(args []string) int {
+	flagSet := newFlagSet("cfg", "[options] <asm file>\n"+
+		"Prints the control flow graph of the asm file, split into basic blocks at labels and jumps.\n"+
+		"Blocks are labeled by their source lines, and edges by their jump conditions.")
+	asJSON := flagSet.Bool("json", false, "print the graph as JSON instead of Graphviz DOT")
+	outFile := flagSet.String("o", stdio, "output file, or - for the standard output")
+	options := newAssemblerFlags(flagSet)
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	asmFile := flagSet.Arg(0)
+
+	reader, err := openInput(asmFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not open asm file: %s\n", err.Error())
+		return exitFailure
+	}
+	defer reader.Close()
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	program, err := config.config.Parse(reader)
+	if err != nil {
+		fprintErrors(os.Stderr, asmFile+": ", err)
+		return exitFailure
+	}
+
+	graph, err := config.config.BuildCFG(program)
+	if err != nil {
+		fprintErrors(os.Stderr, asmFile+": ", err)
+		return exitFailure
+	}
+
+	writer, err := createOutput(*outFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not create output file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	if *asJSON {
+		err = graph.WriteJSON(writer)
+	} else {
+		err = graph.WriteDOT(writer)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not write the graph: %s\n", err.Error())
+		writer.Abort(false)
+		return exitFailure
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not write output file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	return exitOK
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+func runCheck(args []string) int {
+	flagSet := newFlagSet("check", "[options] <asm file>\n"+
+		"Assembles the asm file without writing a hack file and reports its errors and warnings.")
+	options := newAssemblerFlags(flagSet)
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	asmFile := flagSet.Arg(0)
+
+	reader, err := openInput(asmFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not open asm file: %s\n", err.Error())
+		return exitFailure
+	}
+	defer reader.Close()
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	assembler := config.newAssembler(reader, io.Discard)
+
+	err = config.assemble(assembler)
+	printWarnings(assembler)
+	if err != nil {
+		fprintErrors(os.Stderr, asmFile+": ", err)
+		return exitFailure
+	}
+
+	if stripped := assembler.Stripped(); stripped > 0 {
+		fmt.Printf("%s: ok (%d words, %d stripped)\n", asmFile, assembler.Size(), stripped)
+		return exitOK
+	}
+
+	fmt.Printf("%s: ok (%d words)\n", asmFile, assembler.Size())
+	return exitOK
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/yuxki/hack-assembler/pkg/hack"
+)
+
+// loadProfile returns the built-in profile with the given name,
+// or loads the profile from the file at the given path, as TOML if its extension is .toml
+// and as JSON otherwise.
+func loadProfile(nameOrPath string) (hack.Profile, error) {
+	profile, err := hack.LookupProfile(nameOrPath)
+	if err == nil {
+		return profile, nil
+	}
+
+	file, err := os.Open(nameOrPath)
+	if err != nil {
+		return profile, err
+	}
+	defer file.Close()
+
+	if strings.EqualFold(filepath.Ext(nameOrPath), ".toml") {
+		return hack.LoadProfileTOML(file)
+	}
+	return hack.LoadProfile(file)
+}
+
+// loadInstructionSet returns the built-in instruction set with the given name,
+// or loads the encoding table from the file at the given path.
+func loadInstructionSet(nameOrPath string) (hack.Code, error) {
+	code, err := hack.LookupInstructionSet(nameOrPath)
+	if err == nil {
+		return code, nil
+	}
+
+	file, err := os.Open(nameOrPath)
+	if err != nil {
+		return code, err
+	}
+	defer file.Close()
+
+	return hack.LoadCode(file)
+}
+
+// isaFlag adds the option selecting the instruction set to the flag set.
+func isaFlag(flagSet *flag.FlagSet) *string {
+	return flagSet.String("isa", hack.StandardInstructionSetName,
+		"built-in instruction set (standard, extended) or path to a JSON encoding table")
+}
+
+// assemblerFlags are the options of the commands that assemble programs.
+type assemblerFlags struct {
+	flagSet      *flag.FlagSet
+	isa          *string
+	profile      *string
+	singlePass   *bool
+	memoryMap    hack.MemoryMap
+	symbols      symbolsFlag
+	variableBase *uint
+	format       *string
+	strict       *bool
+	strip        *bool
+	maxErrors    *int
+	maxLineLen   *int
+	maxFileSize  *int64
+	tabWidth     *int
+}
+
+// symbolsFlag is a repeatable NAME=ADDRESS option defining symbols.
+type symbolsFlag map[string]uint
+
+func (s symbolsFlag) String() string {
+	definitions := make([]string, 0, len(s))
+	for symbol, address := range s {
+		definitions = append(definitions, fmt.Sprintf("%s=%d", symbol, address))
+	}
+	sort.Strings(definitions)
+
+	return strings.Join(definitions, ",")
+}
+
+func (s symbolsFlag) Set(value string) error {
+	symbol, addressText, ok := strings.Cut(value, "=")
+	if !ok {
+		return fmt.Errorf("%s: expected NAME=ADDRESS", value)
+	}
+
+	address, err := strconv.ParseUint(addressText, 10, 15)
+	if err != nil {
+		return fmt.Errorf("%s: invalid address: %w", value, err)
+	}
+	s[symbol] = uint(address)
+
+	return nil
+}
+
+func newAssemblerFlags(flagSet *flag.FlagSet) *assemblerFlags {
+	f := &assemblerFlags{
+		flagSet:      flagSet,
+		isa:          isaFlag(flagSet),
+		profile:      flagSet.String("profile", hack.StandardProfileName, "standard, bare, or a JSON or TOML profile file"),
+		singlePass:   flagSet.Bool("single-pass", false, "read the asm file once, backpatching forward references to labels"),
+		memoryMap:    hack.StandardMemoryMap(),
+		symbols:      symbolsFlag{},
+		variableBase: flagSet.Uint("variable-base", 16, "address of the first variable"),
+		format:       flagSet.String("format", hack.FormatBinary.String(), "output format (binary, hex, raw)"),
+		strict:       flagSet.Bool("strict", false, "report warnings as errors"),
+		strip:        flagSet.Bool("strip", false, "remove the labeled routines unreachable from the entry point"),
+		maxErrors:    flagSet.Int("max-errors", 1, "number of errors reported before stopping, 0 for all"),
+		maxLineLen:   flagSet.Int("max-line-length", hack.DefaultMaxLineLength, "maximum length of a source line in bytes"),
+		maxFileSize:  flagSet.Int64("max-file-size", 0, "maximum size of an asm file in bytes, 0 for no limit"),
+		tabWidth:     flagSet.Int("tab-width", hack.DefaultTabWidth, "tab stop distance for diagnostic columns"),
+	}
+	flagSet.Var(f.symbols, "symbol", "predefine a symbol as NAME=ADDRESS, may be repeated")
+
+	flagSet.UintVar(&f.memoryMap.ROMSize, "rom-size", f.memoryMap.ROMSize, "ROM size in words")
+	flagSet.UintVar(&f.memoryMap.RAMSize, "ram-size", f.memoryMap.RAMSize, "RAM size in words")
+	flagSet.UintVar(&f.memoryMap.ScreenAddress, "screen", f.memoryMap.ScreenAddress, "screen memory map base address")
+	flagSet.UintVar(&f.memoryMap.KBDAddress, "kbd", f.memoryMap.KBDAddress, "address of the keyboard memory map")
+
+	return f
+}
+
+// loadProfile returns the selected profile with the memory map options applied.
+func (f *assemblerFlags) loadProfile() (hack.Profile, error) {
+	profile, err := loadProfile(*f.profile)
+	if err != nil {
+		return profile, fmt.Errorf("could not load profile: %w", err)
+	}
+
+	// Memory map options override the profile.
+	f.flagSet.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "rom-size":
+			profile.MemoryMap.ROMSize = f.memoryMap.ROMSize
+		case "ram-size":
+			profile.MemoryMap.RAMSize = f.memoryMap.RAMSize
+		case "screen":
+			profile.MemoryMap.ScreenAddress = f.memoryMap.ScreenAddress
+		case "kbd":
+			profile.MemoryMap.KBDAddress = f.memoryMap.KBDAddress
+		}
+	})
+
+	return profile, nil
+}
+
+// assemblerConfig is the configuration selected by the options,
+// loaded once and shared by every assembler.
+type assemblerConfig struct {
+	config     *hack.Config
+	singlePass bool
+
+	// object selects relocatable objects instead of machine code.
+	object bool
+}
+
+// load loads the profile and the instruction set selected by the options,
+// and maps the other options onto the options of the assembler.
+func (f *assemblerFlags) load() (assemblerConfig, error) {
+	profile, err := f.loadProfile()
+	if err != nil {
+		return assemblerConfig{}, err
+	}
+
+	instructionSet, err := loadInstructionSet(*f.isa)
+	if err != nil {
+		return assemblerConfig{}, fmt.Errorf("could not load instruction set: %w", err)
+	}
+
+	if *f.strip && *f.singlePass {
+		return assemblerConfig{}, fmt.Errorf("-strip can not be used with -single-pass")
+	}
+
+	format, err := hack.ParseFormat(*f.format)
+	if err != nil {
+		return assemblerConfig{}, err
+	}
+
+	config, err := hack.NewConfig(
+		hack.WithProfile(profile),
+		hack.WithInstructionSet(instructionSet),
+		hack.WithSymbols(f.symbols),
+		hack.WithVariableBase(*f.variableBase),
+		hack.WithFormat(format),
+		hack.WithStrict(*f.strict),
+		hack.WithStrip(*f.strip),
+		hack.WithMaxErrors(*f.maxErrors),
+		hack.WithMaxLineLength(*f.maxLineLen),
+		hack.WithMaxFileSize(*f.maxFileSize),
+		hack.WithTabWidth(*f.tabWidth),
+	)
+	if err != nil {
+		return assemblerConfig{}, fmt.Errorf("could not create assembler: %w", err)
+	}
+
+	return assemblerConfig{config: config, singlePass: *f.singlePass}, nil
+}
+
+// newAssembler creates an assembler with the loaded profile and instruction set.
+func (c assemblerConfig) newAssembler(r io.Reader, w io.Writer) *hack.Assembler {
+	return c.config.NewAssembler(r, w)
+}
+
+// assemble runs the assembler in single pass if selected by the options,
+// or writes a relocatable object if selected.
+func (c assemblerConfig) assemble(assembler *hack.Assembler) error {
+	if c.object {
+		return assembler.AssembleObject()
+	}
+	if c.singlePass {
+		return assembler.AssembleSinglePass()
+	}
+
+	return assembler.Assemble()
+}
+
+// openInput opens the file at the given path, or the standard input if the path is stdio.
+func openInput(path string) (io.ReadCloser, error) {
+	if path == stdio {
+		return io.NopCloser(os.Stdin), nil
+	}
+
+	return os.Open(path)
+}
+
+func printWarnings(assembler *hack.Assembler) {
+	fprintWarnings(os.Stderr, assembler)
+}
+
+func fprintWarnings(w io.Writer, assembler *hack.Assembler) {
+	for _, warning := range assembler.Warnings() {
+		fmt.Fprintf(w, "Warning: %s\n", warning.Error())
+	}
+}
+
+// fprintErrors writes each error reported by an assembly on its own line, after the prefix.
+func fprintErrors(w io.Writer, prefix string, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(w, "Error: %s%s\n", prefix, e.Error())
+	}
+}
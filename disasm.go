@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/yuxki/hack-assembler/pkg/hack"
+)
+
+func runDisasm(args []string) int {
+	flagSet := newFlagSet("disasm", "[options] <hack file>\n"+
+		"Use - as the hack file to read from the standard input.")
+	outFile := flagSet.String("o", stdio, "output file, or - for the standard output")
+	isa := isaFlag(flagSet)
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	code, err := loadInstructionSet(*isa)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not load instruction set: %s\n", err.Error())
+		return exitFailure
+	}
+
+	reader, err := openInput(flagSet.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not open hack file: %s\n", err.Error())
+		return exitFailure
+	}
+	defer reader.Close()
+
+	writer, err := createOutput(*outFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not create asm file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	disassembler := hack.NewDisassembler(reader, writer)
+	disassembler.SetInstructionSet(code)
+
+	err = disassembler.Disassemble()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not disassemble file: %s\n", err.Error())
+		writer.Abort(false)
+		return exitFailure
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not write asm file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	return exitOK
+}
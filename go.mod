@@ -2,4 +2,7 @@ module github.com/yuxki/hack-assembler
 
 go 1.21.1
 
-require github.com/google/go-cmp v0.6.0
+require (
+	github.com/BurntSushi/toml v1.3.2
+	github.com/google/go-cmp v0.6.0
+)
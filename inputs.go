@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// hasMeta returns true if the path is a pattern rather than a file name.
+func hasMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
+
+// expandInputs returns the asm files named by the arguments, without duplicates.
+// An argument is an asm file, stdio, a directory searched recursively for .asm files,
+// or a pattern in which ** matches any number of directories.
+func expandInputs(args []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+
+	for _, arg := range args {
+		var matches []string
+		var err error
+
+		switch {
+		case arg == stdio:
+			matches = []string{arg}
+		case hasMeta(arg):
+			matches, err = globFiles(arg)
+			if err == nil && len(matches) == 0 {
+				err = fmt.Errorf("no asm file matches %s", arg)
+			}
+		case isDir(arg):
+			matches, err = globFiles(filepath.Join(arg, "**", "*.asm"))
+		default:
+			matches = []string{arg}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// globFiles returns the regular files matching the pattern, in lexical order.
+func globFiles(pattern string) ([]string, error) {
+	parts := strings.Split(filepath.ToSlash(pattern), "/")
+
+	// Walk from the longest directory prefix without patterns.
+	static := 0
+	for static < len(parts)-1 && !hasMeta(parts[static]) {
+		static++
+	}
+	root := strings.Join(parts[:static], "/")
+	if root == "" && static > 0 {
+		root = "/"
+	}
+	if root == "" {
+		root = "."
+	}
+
+	if !isDir(root) {
+		return nil, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(filepath.FromSlash(root), func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(filepath.FromSlash(root), p)
+		if err != nil {
+			return err
+		}
+
+		ok, err := matchParts(parts[static:], strings.Split(filepath.ToSlash(rel), "/"))
+		if err != nil {
+			return err
+		}
+		if ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchParts reports whether the path elements match the pattern elements,
+// where a ** element matches zero or more path elements.
+func matchParts(pattern []string, name []string) (bool, error) {
+	if len(pattern) == 0 {
+		return len(name) == 0, nil
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(name); i++ {
+			ok, err := matchParts(pattern[1:], name[i:])
+			if ok || err != nil {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+
+	if len(name) == 0 {
+		return false, nil
+	}
+
+	ok, err := path.Match(pattern[0], name[0])
+	if !ok || err != nil {
+		return false, err
+	}
+
+	return matchParts(pattern[1:], name[1:])
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchParts(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		pattern  []string
+		name     []string
+		match    bool
+	}{
+		{"no directory", []string{"**", "*.asm"}, []string{"a.asm"}, true},
+		{"nested directories", []string{"**", "*.asm"}, []string{"a", "b", "c.asm"}, true},
+		{"empty match inside", []string{"src", "**", "main.asm"}, []string{"src", "main.asm"}, true},
+		{"star one directory", []string{"src", "*", "main.asm"}, []string{"src", "a", "b", "main.asm"}, false},
+		{"other extension", []string{"**", "*.asm"}, []string{"a", "notes.txt"}, false},
+		{"trailing path", []string{"*.asm"}, []string{"a.asm", "b.asm"}, false},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			match, err := matchParts(d.pattern, d.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if match != d.match {
+				t.Errorf("expected %t, got %t", d.match, match)
+			}
+		})
+	}
+}
+
+func TestMatchParts_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := matchParts([]string{"["}, []string{"a.asm"})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+// writeFiles creates the files with the given slash-separated paths in dir.
+func writeFiles(t *testing.T, dir string, files ...string) {
+	t.Helper()
+
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		err := os.MkdirAll(filepath.Dir(path), outDirMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte("@1\n"), hackFileMode)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExpandInputs(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "a.asm", "sub/b.asm", "sub/deep/c.asm", "sub/notes.txt")
+
+	path := func(file string) string {
+		return filepath.Join(dir, filepath.FromSlash(file))
+	}
+
+	data := []struct {
+		testCase string
+		args     []string
+		files    []string
+	}{
+		{"file", []string{path("a.asm")}, []string{path("a.asm")}},
+		{"stdio", []string{stdio}, []string{stdio}},
+		{"directory", []string{dir}, []string{path("a.asm"), path("sub/b.asm"), path("sub/deep/c.asm")}},
+		{"double star", []string{path("**/*.asm")}, []string{path("a.asm"), path("sub/b.asm"), path("sub/deep/c.asm")}},
+		{"double star inside", []string{path("sub/**/c.asm")}, []string{path("sub/deep/c.asm")}},
+		{"star", []string{path("sub/*.asm")}, []string{path("sub/b.asm")}},
+		{"duplicates", []string{path("sub/b.asm"), path("sub")}, []string{path("sub/b.asm"), path("sub/deep/c.asm")}},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			files, err := expandInputs(d.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(files, d.files); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestExpandInputs_NoMatch(t *testing.T) {
+	t.Parallel()
+
+	_, err := expandInputs([]string{filepath.Join(t.TempDir(), "**", "*.asm")})
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
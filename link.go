@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuxki/hack-assembler/pkg/hack"
+)
+
+// linkPath returns the default path of the hack file linked from the objects:
+// the path of the first object with its .o extension replaced with .hack.
+func linkPath(objectFile string) string {
+	return strings.TrimSuffix(objectFile, filepath.Ext(objectFile)) + ".hack"
+}
+
+// readObject reads the object file at the given path.
+func readObject(path string) (*hack.Object, error) {
+	reader, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	object, err := hack.ReadObject(reader)
+	if err != nil {
+		return nil, err
+	}
+	object.Name = path
+
+	return object, nil
+}
+
+func runLink(args []string) int {
+	flagSet := newFlagSet("link", "[options] <object file>...\n"+
+		"Links the objects written by asm -c into a hack file, in the order of the object files.")
+	outFile := flagSet.String("o", "",
+		"output file, or - for the standard output (default: the first object file with .hack)")
+	options := newAssemblerFlags(flagSet)
+
+	// Options may follow the object files, as in link a.o b.o -o prog.hack.
+	objectFiles, ok, code := parseInterspersedFlags(flagSet, args)
+	if !ok {
+		return code
+	}
+
+	if len(objectFiles) == 0 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	hackFile := *outFile
+	if hackFile == "" {
+		hackFile = linkPath(objectFiles[0])
+	}
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	objects := make([]*hack.Object, 0, len(objectFiles))
+	for _, objectFile := range objectFiles {
+		object, err := readObject(objectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: could not read object file: %s: %s\n", objectFile, err.Error())
+			return exitFailure
+		}
+		objects = append(objects, object)
+	}
+
+	writer, err := createOutput(hackFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not create hack file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	linker := config.config.NewLinker(writer)
+
+	err = linker.Link(objects...)
+	for _, warning := range linker.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning.Error())
+	}
+	if err != nil {
+		writer.Abort(false)
+		fprintErrors(os.Stderr, "could not link objects: ", err)
+		return exitFailure
+	}
+
+	err = writer.Commit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not write hack file: %s\n", err.Error())
+		return exitFailure
+	}
+
+	return exitOK
+}
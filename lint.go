@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/yuxki/hack-assembler/pkg/hack"
+)
+
+// splitRules splits a comma-separated list of lint rules.
+func splitRules(list string) []string {
+	var rules []string
+	for _, rule := range strings.Split(list, ",") {
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+// lintFile lints the asm file and prints its diagnostics.
+// It returns the number of diagnostics, or an error if the file can not be parsed.
+func lintFile(config assemblerConfig, linter *hack.Linter, asmFile string) (int, error) {
+	reader, err := openInput(asmFile)
+	if err != nil {
+		return 0, fmt.Errorf("could not open asm file: %w", err)
+	}
+	defer reader.Close()
+
+	program, err := config.config.Parse(reader)
+	if err != nil {
+		return 0, err
+	}
+
+	diagnostics := linter.Lint(program)
+	for _, d := range diagnostics {
+		fmt.Printf("%s: %s\n", asmFile, d.String())
+	}
+
+	return len(diagnostics), nil
+}
+
+func runLint(args []string) int {
+	flagSet := newFlagSet("lint", "[options] <asm file | directory | pattern>...\n"+
+		"Reports likely mistakes in asm files. Diagnostics are suppressed by a // hack:ignore [rule...] comment\n"+
+		"at the end of their line, or on the line before.")
+	disable := flagSet.String("disable", "", "comma-separated rules to disable")
+	enable := flagSet.String("enable", "", "comma-separated rules to enable, disabling the others")
+	list := flagSet.Bool("list", false, "list the rules and exit")
+	options := newAssemblerFlags(flagSet)
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
+	}
+
+	if *list {
+		for _, rule := range hack.LintRules() {
+			fmt.Printf("%-20s %s\n", rule.Name, rule.Description)
+		}
+		return exitOK
+	}
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	linter := config.config.NewLinter()
+	if *enable != "" {
+		for _, rule := range hack.LintRules() {
+			_ = linter.Disable(rule.Name)
+		}
+		err = linter.Enable(splitRules(*enable)...)
+	}
+	if err == nil {
+		err = linter.Disable(splitRules(*disable)...)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitUsage
+	}
+
+	asmFiles, err := expandInputs(flagSet.Args())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	code := exitOK
+	for _, asmFile := range asmFiles {
+		count, err := lintFile(config, linter, asmFile)
+		if err != nil {
+			fprintErrors(os.Stderr, asmFile+": ", err)
+		}
+		if err != nil || count > 0 {
+			code = exitFailure
+		}
+	}
+
+	return code
+}
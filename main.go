@@ -1,55 +1,176 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
+)
+
+// version is set at build time.
+var version = "dev"
 
-	"github.com/yuxki/hack-assembler/pkg/hack"
+// Exit codes.
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
 )
 
-func printUsage() {
-	fmt.Println("Usage: hack-assembler <asm file>")
+// stdio is the path that stands for the standard input or the standard output.
+const stdio = "-"
+
+// command is a subcommand of hack-assembler.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		printUsage()
-		return
+var commands []command
+
+func init() {
+	commands = []command{
+		{name: "asm", summary: "assemble an asm file into a hack file", run: runAsm},
+		{name: "link", summary: "link object files into a hack file", run: runLink},
+		{name: "disasm", summary: "disassemble a hack file into an asm file", run: runDisasm},
+		{name: "symbols", summary: "print the symbol table of an asm file", run: runSymbols},
+		{name: "lint", summary: "report likely mistakes in asm files", run: runLint},
+		{name: "cfg", summary: "print the control flow graph of an asm file", run: runCFG},
+		{name: "check", summary: "check an asm file without writing a hack file", run: runCheck},
+		{name: "version", summary: "print the version", run: runVersion},
+		{name: "help", summary: "print the help of a command", run: runHelp},
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
 	}
 
-	asmFile := os.Args[1]
+	return command{}, false
+}
 
-	if !strings.HasSuffix(asmFile, ".asm") {
-		fmt.Printf("Error: file must have .asm extension: %s\n", asmFile)
-		return
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "Usage: hack-assembler <command> [options] [arguments]")
+	fmt.Fprintln(os.Stderr, "       hack-assembler [asm options] <asm file>")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", c.name, c.summary)
 	}
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Run 'hack-assembler help <command>' for the options of a command.")
+}
 
-	if _, err := os.Stat(asmFile); os.IsNotExist(err) {
-		fmt.Printf("Error: asm file does not exist: %s\n", asmFile)
-		return
+// newFlagSet returns a flag set of the command that prints the given usage line
+// followed by the options of the command.
+func newFlagSet(name string, usage string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: hack-assembler %s %s\n", name, usage)
+		flagSet.PrintDefaults()
 	}
 
-	reader, err := os.Open(asmFile)
+	return flagSet
+}
+
+// parseFlags parses the arguments of a command.
+// It returns false and the exit code if the command should not run.
+func parseFlags(flagSet *flag.FlagSet, args []string) (bool, int) {
+	err := flagSet.Parse(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return false, exitOK
+	}
 	if err != nil {
-		fmt.Printf("Error: could not open asm file: %s\n", err.Error())
+		return false, exitUsage
 	}
-	defer reader.Close()
 
-	outFile := strings.Replace(asmFile, ".asm", ".hack", 1)
-	writer, err := os.Create(outFile)
-	if err != nil {
-		fmt.Printf("Error: could not create hack file: %s\n", err.Error())
+	return true, exitOK
+}
+
+// parseInterspersedFlags is like parseFlags, but the flags may follow the arguments,
+// as in asm prog.asm -o prog.hack. It returns the arguments left.
+func parseInterspersedFlags(flagSet *flag.FlagSet, args []string) ([]string, bool, int) {
+	var rest []string
+	for {
+		if ok, code := parseFlags(flagSet, args); !ok {
+			return nil, false, code
+		}
+		args = flagSet.Args()
+		if len(args) == 0 {
+			return rest, true, exitOK
+		}
+		rest = append(rest, args[0])
+		args = args[1:]
 	}
-	defer writer.Close()
+}
 
-	assmbler, err := hack.NewAssembler(reader, writer)
-	if err != nil {
-		panic("Error: could not create assembler: " + err.Error())
+func runVersion(args []string) int {
+	flagSet := newFlagSet("version", "")
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
 	}
 
-	err = assmbler.Assemble()
-	if err != nil {
-		fmt.Printf("Error: could not assemble file: %s\n", err.Error())
+	fmt.Printf("hack-assembler %s\n", version)
+	return exitOK
+}
+
+func runHelp(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		printUsage()
+		return exitOK
+	}
+
+	c, ok := lookupCommand(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command: %s\n", args[0])
+		return exitUsage
+	}
+
+	return c.run([]string{"-h"})
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// run runs the command named by the first argument and returns the exit code.
+// Arguments that do not start with a command are run by asm, for backwards compatibility.
+func run(args []string) int {
+	if len(args) == 0 {
+		printUsage()
+		return exitUsage
 	}
+
+	if c, ok := lookupCommand(args[0]); ok {
+		return c.run(args[1:])
+	}
+
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		printUsage()
+		return exitOK
+	}
+
+	if isAsmAlias(args[0]) {
+		return runAsm(args)
+	}
+
+	fmt.Fprintf(os.Stderr, "Error: unknown command: %s\n", args[0])
+	printUsage()
+	return exitUsage
+}
+
+// isAsmAlias returns true if the first argument is an option or an asm file,
+// so that hack-assembler file.asm keeps working as hack-assembler asm file.asm.
+func isAsmAlias(arg string) bool {
+	if strings.HasPrefix(arg, "-") || strings.HasSuffix(arg, ".asm") {
+		return true
+	}
+
+	_, err := os.Stat(arg)
+	return err == nil
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const hackFileMode = 0o644
+
+// output is the destination of a hack file.
+// Everything is written to a temporary file, or buffered for the standard output,
+// and the hack file is replaced only on Commit,
+// so that a failing assembly never leaves a truncated hack file behind.
+type output struct {
+	path   string
+	temp   *os.File
+	buffer *bytes.Buffer
+}
+
+// createOutput creates an output for the hack file at the given path,
+// or for the standard output if the path is stdio.
+// The temporary file is created next to the hack file so that it can be renamed atomically.
+func createOutput(path string) (*output, error) {
+	if path == stdio {
+		return &output{path: path, buffer: &bytes.Buffer{}}, nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	// CreateTemp creates files readable only by the owner.
+	err = temp.Chmod(hackFileMode)
+	if err != nil {
+		temp.Close()
+		os.Remove(temp.Name())
+		return nil, err
+	}
+
+	return &output{path: path, temp: temp}, nil
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	if o.temp == nil {
+		return o.buffer.Write(p)
+	}
+	return o.temp.Write(p)
+}
+
+// Commit replaces the hack file with everything written so far.
+func (o *output) Commit() error {
+	if o.temp == nil {
+		_, err := io.Copy(os.Stdout, o.buffer)
+		return err
+	}
+
+	err := o.temp.Close()
+	if err != nil {
+		os.Remove(o.temp.Name())
+		return err
+	}
+
+	err = os.Rename(o.temp.Name(), o.path)
+	if err != nil {
+		os.Remove(o.temp.Name())
+		return err
+	}
+
+	return nil
+}
+
+// Abort discards everything written so far.
+// If keepPartial is true, the partial output is committed instead
+// and the path it was written to is returned.
+func (o *output) Abort(keepPartial bool) string {
+	if keepPartial && o.Commit() == nil {
+		return o.path
+	}
+
+	if o.temp != nil {
+		o.temp.Close()
+		os.Remove(o.temp.Name())
+	}
+
+	return ""
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// readDir returns the names of the files in dir, and the content of the file name.
+func readDir(t *testing.T, dir string, name string) ([]string, string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return names, string(content)
+}
+
+func TestOutput(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		finish   func(o *output) error
+		content  string
+	}{
+		{
+			testCase: "commit",
+			finish:   (*output).Commit,
+			content:  "new\n",
+		},
+		{
+			testCase: "abort",
+			finish: func(o *output) error {
+				o.Abort(false)
+				return nil
+			},
+			content: "old\n",
+		},
+		{
+			testCase: "abort keeping the partial output",
+			finish: func(o *output) error {
+				o.Abort(true)
+				return nil
+			},
+			content: "new\n",
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			hackFile := filepath.Join(dir, "prog.hack")
+			err := os.WriteFile(hackFile, []byte("old\n"), hackFileMode)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o, err := createOutput(hackFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = o.Write([]byte("new\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = d.finish(o)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			names, content := readDir(t, dir, "prog.hack")
+			if diff := cmp.Diff(names, []string{"prog.hack"}); diff != "" {
+				t.Errorf("temporary file left: %s", diff)
+			}
+			if content != d.content {
+				t.Errorf("expected %q, got %q", d.content, content)
+			}
+		})
+	}
+}
+
+func TestRunAsm_FailureKeepsHackFile(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	asmFile := filepath.Join(dir, "prog.asm")
+	err := os.WriteFile(asmFile, []byte("@1\nD=X\n"), hackFileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "prog.hack"), []byte("old\n"), hackFileMode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runAsm([]string{asmFile}); code != exitFailure {
+		t.Errorf("expected exit code %d, got %d", exitFailure, code)
+	}
+
+	names, content := readDir(t, dir, "prog.hack")
+	if diff := cmp.Diff(names, []string{"prog.asm", "prog.hack"}); diff != "" {
+		t.Errorf("temporary file left: %s", diff)
+	}
+	if content != "old\n" {
+		t.Errorf("expected the hack file to be untouched, got %q", content)
+	}
+}
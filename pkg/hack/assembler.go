@@ -1,23 +1,31 @@
 package hack
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"regexp"
-	"strconv"
-	"strings"
 )
 
 // Assembler is a struct that assembles Hack assembly code into Hack machine code.
-// It uses a Parser to parse the assembly code, a Code to translate the parsed commands into binary,
+// It uses a Parser to parse the assembly code, an InstructionSet to translate the parsed commands into binary,
 // and a SymbolTable to keep track of symbols and their addresses.
+// An Assembler can assemble several programs one after another with Reset,
+// but must not be used from multiple goroutines; use a Config to create one per goroutine.
 type Assembler struct {
-	w           io.Writer
-	parser      *Parser
-	code        Code
+	w      io.Writer
+	parser *Parser
+	config *Config
+	code   InstructionSet
+
+	// The state of the current program, reset by each assembly.
 	symbolTable *SymbolTable
+	namespaces  *namespaces
 	nextAddress uint
+	warnings    []error
+	size        int
+	stripped    int
 }
 
 const (
@@ -26,7 +34,6 @@ const (
 	argAddress  = 2
 	thisAddress = 3
 	thatAddress = 4
-	kbdAddress  = 24576
 
 	initialNextAddress = 16
 
@@ -36,211 +43,480 @@ const (
 // NewAssembler creates a new instance of the Assembler.
 // It takes a reader and a writer as input parameters.
 // The reader is used to read the assembly code, while the writer is used to write the machine code.
+// The assembler is configured for the standard Hack computer, unless changed by the options.
 // It returns a pointer to the Assembler and an error (if any) occurred during initialization.
-func NewAssembler(r io.Reader, w io.Writer) (*Assembler, error) {
-	parser := NewParser(r)
-	code := NewCode()
-	table := NewSymbolTable()
-
-	err := table.AddEntry("SP", spAddress)
+func NewAssembler(r io.Reader, w io.Writer, opts ...Option) (*Assembler, error) {
+	config, err := NewConfig(opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	err = table.AddEntry("LCL", lclAddress)
-	if err != nil {
-		return nil, err
+	return config.NewAssembler(r, w), nil
+}
+
+// NewAssemblerWithMemoryMap creates a new instance of the Assembler for a Hack computer
+// with the given memory map.
+// The SCREEN and KBD symbols are bound to the addresses of the memory map.
+func NewAssemblerWithMemoryMap(r io.Reader, w io.Writer, m MemoryMap) (*Assembler, error) {
+	return NewAssembler(r, w, WithMemoryMap(m))
+}
+
+// NewAssemblerWithProfile creates a new instance of the Assembler for the Hack computer
+// described by the profile.
+// The symbol table is initialized with the predefined symbols of the profile.
+func NewAssemblerWithProfile(r io.Reader, w io.Writer, profile Profile) (*Assembler, error) {
+	return NewAssembler(r, w, WithProfile(profile))
+}
+
+// Reset makes the Assembler read the next program from r and write its machine code to w.
+// The labels and the variables of the previous program are forgotten.
+func (a *Assembler) Reset(r io.Reader, w io.Writer) {
+	a.parser.Reset(r)
+	a.w = w
+	a.reset()
+}
+
+// reset clears the state of the previous program.
+func (a *Assembler) reset() {
+	a.symbolTable = newScopedSymbolTable(a.config.predefined)
+	a.namespaces = nil
+	a.nextAddress = a.config.variableBase
+	a.warnings = nil
+	a.size = 0
+	a.stripped = 0
+}
+
+// ErrInvalidCommand is returned when the parser encounters an invalid command.
+var ErrInvalidCommand = errors.New("invalid command")
+
+// newSourceError returns a SourceError positioned at the node.
+func newSourceError(node Node, format string, a ...any) error {
+	pos := node.Pos()
+	return &SourceError{Line: pos.Line, Column: pos.Column, Err: fmt.Errorf(format, a...)}
+}
+
+func (a *Assembler) assembleACommand(command *AInstruction) (uint16, error) {
+	if command.Symbol == "" {
+		return EncodeWith(a.code, command)
 	}
 
-	err = table.AddEntry("ARG", argAddress)
-	if err != nil {
-		return nil, err
+	symbol := command.Symbol
+	if a.symbolTable.Contains(symbol) {
+		address, err := a.symbolTable.GetAddress(symbol)
+		if err != nil {
+			return 0, err
+		}
+		return uint16(address), nil
 	}
 
-	err = table.AddEntry("THIS", thisAddress)
-	if err != nil {
-		return nil, err
+	if a.nextAddress >= a.config.memoryMap.RAMSize {
+		return 0, fmt.Errorf("no RAM left for variable %s: %w", symbol, ErrRAMOverflow)
+	}
+	if warning := a.config.memoryMap.checkVariable(a.nextAddress); warning != nil {
+		if a.config.strict {
+			return 0, fmt.Errorf("%s at %d: %w", symbol, a.nextAddress, warning)
+		}
+		a.warnings = append(a.warnings, newSourceError(command, "%s at %d: %w", symbol, a.nextAddress, warning))
 	}
 
-	err = table.AddEntry("THAT", thatAddress)
+	err := a.symbolTable.AddEntry(symbol, a.nextAddress)
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
+	address := a.nextAddress
+	a.nextAddress++
 
-	err = table.AddEntry("SCREEN", screenAddress)
-	if err != nil {
-		return nil, err
+	return uint16(address), nil
+}
+
+func (a *Assembler) assembleCCommand(command *CInstruction) (uint16, error) {
+	if command.Dest+command.Jump == "" {
+		return 0, fmt.Errorf("no dest or jump: %w", ErrInvalidCommand)
 	}
 
-	err = table.AddEntry("KBD", kbdAddress)
+	return EncodeWith(a.code, command)
+}
+
+// SetInstructionSet sets the instruction set used to parse and translate C commands.
+// The standard Hack instruction set is used by default.
+func (a *Assembler) SetInstructionSet(is InstructionSet) {
+	a.code = is
+	a.parser.SetInstructionSet(is)
+}
+
+// SymbolTable returns the symbol table built by the last call to Assemble.
+func (a *Assembler) SymbolTable() *SymbolTable {
+	return a.symbolTable
+}
+
+// Warnings returns the warnings reported by the last call to Assemble.
+// Each warning is a SourceError wrapping ErrScreenVariable or ErrKeyboardVariable.
+// With WithStrict, warnings are returned as errors instead.
+func (a *Assembler) Warnings() []error {
+	return a.warnings
+}
+
+// Size returns the number of instructions written by the last call to Assemble.
+func (a *Assembler) Size() int {
+	return a.size
+}
+
+// Stripped returns the number of instructions removed by the last call to Assemble with WithStrip.
+func (a *Assembler) Stripped() int {
+	return a.stripped
+}
+
+// Assemble function takes the Hack assembly code as input and converts it
+// into Hack machine code.
+// It then writes the machine code to the writer provided by the Assembler.
+// If the assembly code is invalid, it will return an error.
+// To accomplish this, it performs the following steps:
+// 1. Parsing of the assembly code into a Program.
+// 2. Creation of a symbol table.
+// 3. Translation of the parsed code into binary.
+// If the translation fails, the instructions translated before the failing one are written,
+// and on syntax errors, the instructions before the first invalid line.
+// Up to the number of errors set by WithMaxErrors are reported.
+func (a *Assembler) Assemble() error {
+	return a.AssembleContext(context.Background())
+}
+
+// AssembleContext is like Assemble, but stops with the error of ctx when ctx is done.
+// The reader is not interrupted, so a blocking read delays the cancellation until it returns.
+func (a *Assembler) AssembleContext(ctx context.Context) error {
+	program, partial, err := parsePartial(ctx, a.parser, a.config.maxErrors)
 	if err != nil {
-		return nil, err
+		if program == nil {
+			return err
+		}
+		if werr := a.writePartial(ctx, program, partial); werr != nil {
+			return werr
+		}
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
-	var i uint
-	for i = 0; i < ramAddressNumbers; i++ {
-		err = table.AddEntry(fmt.Sprintf("R%d", i), i)
-		if err != nil {
-			return nil, err
-		}
+	words, err := a.assembleProgram(program)
+
+	if werr := a.writeWords(words); werr != nil {
+		return werr
 	}
 
-	return &Assembler{
-			w:           w,
-			parser:      parser,
-			code:        code,
-			symbolTable: table,
-			nextAddress: initialNextAddress,
-		},
-		nil
+	return err
 }
 
-// ErrInvalidCommand is returned when the parser encounters an invalid command.
-var ErrInvalidCommand = errors.New("invalid command")
+// AssembleSinglePass is like Assemble, but it reads the assembly code exactly once,
+// so that the reader can be a non-seekable stream.
+// Instructions are translated into an in-memory ROM as they are read.
+// A-instructions referring to symbols not defined yet are recorded and
+// backpatched when the label is defined, and the symbols still undefined at the end
+// are allocated as variables in the order of their first use.
+// The machine code is the same as the one written by Assemble.
+// Unlike Assemble, nothing is written if the assembly fails,
+// and directives are reported as errors wrapping ErrUnsupportedDirective.
+func (a *Assembler) AssembleSinglePass() error {
+	return a.AssembleSinglePassContext(context.Background())
+}
 
-func uintAddressToACommandBinary(address uint) string {
-	return fmt.Sprintf("0%015b", address)
+// AssembleSinglePassContext is like AssembleSinglePass, but stops with the error of ctx when ctx is done.
+func (a *Assembler) AssembleSinglePassContext(ctx context.Context) error {
+	words, err := a.assembleStream(ctx, a.parser)
+	if err != nil {
+		return err
+	}
+
+	return a.writeWords(words)
 }
 
-func intAddressToACommandBinary(address int) string {
-	return fmt.Sprintf("0%015b", address)
+// writeWords writes the words in the format of the Assembler to its writer.
+func (a *Assembler) writeWords(words []uint16) error {
+	a.size = len(words)
+	return writeWords(a.w, a.config.format, words)
 }
 
-func (a *Assembler) assembleACommand() (string, error) {
-	var binary string
+// writePartial writes the instructions of the program before its first syntax error.
+// The rest of the input is read so that the labels defined after the error are known,
+// and its lines are parsed without reporting their errors.
+// Nothing is written with WithStrip, since the addresses depend on the whole program.
+func (a *Assembler) writePartial(ctx context.Context, program *Program, partial int) error {
+	if a.config.strip {
+		return nil
+	}
 
-	symbol, err := a.parser.Symbol()
-	if err != nil {
-		return "", err
+	for a.parser.Advance() && ctx.Err() == nil {
+		if node, err := a.parser.Node(); err == nil {
+			program.Nodes = append(program.Nodes, node)
+		}
 	}
-	if regexp.MustCompile(`^\d+$`).MatchString(symbol) {
-		address, err := strconv.Atoi(symbol)
-		if err != nil {
-			return "", err
+
+	count := 0
+	for _, node := range program.Nodes[:partial] {
+		switch node.(type) {
+		case *AInstruction, *CInstruction:
+			count++
 		}
-		return intAddressToACommandBinary(address), nil
 	}
 
-	if a.symbolTable.Contains(symbol) {
-		address, err := a.symbolTable.GetAddress(symbol)
+	words, _ := a.assembleProgram(program)
+	return a.writeWords(words[:min(count, len(words))])
+}
+
+// writeWords writes the words in the format to w.
+func writeWords(w io.Writer, format Format, words []uint16) error {
+	bw := bufio.NewWriter(w)
+	line := make([]byte, instructionBits+1)
+	for _, word := range words {
+		_, err := bw.Write(format.appendWord(line[:0], word))
 		if err != nil {
-			return "", err
+			return err
 		}
-		return uintAddressToACommandBinary(address), nil
 	}
 
-	err = a.symbolTable.AddEntry(symbol, a.nextAddress)
-	if err != nil {
-		return "", err
+	return bw.Flush()
+}
+
+// appendBinary appends the word as a line of instructionBits binary digits to dst.
+func appendBinary(dst []byte, word uint16) []byte {
+	for bit := instructionBits - 1; bit >= 0; bit-- {
+		dst = append(dst, '0'+byte(word>>bit&1))
 	}
-	binary = uintAddressToACommandBinary(a.nextAddress)
-	a.nextAddress++
 
-	return binary, nil
+	return append(dst, '\n')
 }
 
-func (a *Assembler) assembleCCommand() (string, error) {
-	dest, err := a.parser.Dest()
-	if err != nil {
-		return "", err
-	}
-	dBits, err := a.code.Dest(dest)
-	if err != nil {
-		return "", err
-	}
+// assembleProgram translates the program into machine code.
+// On error, it returns the instructions translated before the first failing one.
+func (a *Assembler) assembleProgram(program *Program) ([]uint16, error) {
+	a.reset()
+	errs := errorList{max: a.config.maxErrors}
 
-	comp, err := a.parser.Comp()
-	if err != nil {
-		return "", err
+	program = a.includeLibrary(program, &errs)
+	if a.config.strip && len(errs.errs) == 0 {
+		program = a.strip(program, &errs)
 	}
-	cBits, err := a.code.Comp(comp)
-	if err != nil {
-		return "", err
+
+	count := a.createSymbolTable(program, &errs)
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
 	}
 
-	jump, err := a.parser.Jump()
-	if err != nil {
-		return "", err
+	words := make([]uint16, 0, count)
+	file := ""
+	for _, node := range program.Nodes {
+		var word uint16
+		var err error
+
+		file = nextFile(node, file)
+		switch command := node.(type) {
+		case *AInstruction:
+			word, err = a.assembleACommand(a.qualify(command, file))
+		case *CInstruction:
+			word, err = a.assembleCCommand(command)
+		default:
+			continue
+		}
+		if err != nil {
+			if errs.add(newSourceError(node, "%s: %w", node, err)) {
+				break
+			}
+			continue
+		}
+
+		if len(errs.errs) == 0 {
+			words = append(words, word)
+		}
 	}
-	jBits, err := a.code.Jump(jump)
-	if err != nil {
-		return "", err
+
+	return words, errs.err()
+}
+
+// createSymbolTable function creates a symbol table from the program.
+// It does this by adding each label to the symbol table with the address of
+// the instruction following it, qualified with the name of its file if it is private.
+// It returns the number of instructions of the program, and adds its errors to errs.
+func (a *Assembler) createSymbolTable(program *Program, errs *errorList) uint {
+	var address uint
+
+	a.namespaces = newNamespaces(program, errs)
+	if len(errs.errs) > 0 {
+		return address
 	}
 
-	if dest+jump == "" {
-		return "", fmt.Errorf("no dest or jump: %w", ErrInvalidCommand)
+	file := ""
+	for _, node := range program.Nodes {
+		file = nextFile(node, file)
+		switch command := node.(type) {
+		case *Label:
+			if address >= a.config.memoryMap.ROMSize {
+				errs.add(newSourceError(node, "label %s resolves to %d: %w", command.Name, address, ErrROMOverflow))
+				return address
+			}
+			err := a.symbolTable.AddEntry(a.namespaces.label(file, command.Name), address)
+			if err != nil && errs.add(newSourceError(node, "%s: %w", node, err)) {
+				return address
+			}
+		case *AInstruction, *CInstruction:
+			address++
+			if address > a.config.memoryMap.ROMSize {
+				errs.add(newSourceError(node, "program exceeds %d words: %w", a.config.memoryMap.ROMSize, ErrROMOverflow))
+				return address
+			}
+		}
 	}
 
-	return fmt.Sprintf("111%s%s%s", cBits, dBits, jBits), nil
+	return address
 }
 
-// Assemble function takes the Hack assembly code as input and converts it
-// into Hack machine code.
-// It then writes the machine code to the writer provided by the Assembler.
-// If the assembly code is invalid, it will return an error.
-// To accomplish this, it performs the following steps:
-// 1. Creation of a symbol table.
-// 2. Parsing of the assembly code.
-// 3. Translation of the parsed code into binary.
-func (a *Assembler) Assemble() error {
-	err := a.createSymbolTable()
-	if err != nil {
-		return err
+// forwardReference is a symbol used by A-instructions before its definition.
+type forwardReference struct {
+	// first is the A-instruction using the symbol first.
+	first *AInstruction
+
+	// addresses are the ROM addresses of the A-instructions using the symbol.
+	addresses []uint
+
+	resolved bool
+}
+
+// stream is the state of a single-pass assembly.
+type stream struct {
+	rom []uint16
+
+	// order are the forward references in the order of their first use.
+	order []*forwardReference
+
+	// references maps the symbols not defined yet to their forward references.
+	references map[string]*forwardReference
+}
+
+// addReference records the A-instruction at the address as a use of a symbol not defined yet.
+func (s *stream) addReference(command *AInstruction, address uint) {
+	reference, ok := s.references[command.Symbol]
+	if !ok {
+		reference = &forwardReference{first: command}
+		s.references[command.Symbol] = reference
+		s.order = append(s.order, reference)
 	}
+	reference.addresses = append(reference.addresses, address)
+}
 
-	for a.parser.Advance() {
-		binary := ""
-		switch a.parser.CommandType() {
-		case ACommand:
-			binary, err = a.assembleACommand()
-			if err != nil {
-				return err
-			}
-		case CCommand:
-			binary, err = a.assembleCCommand()
-			if err != nil {
-				return err
+// assembleStream translates the commands read by the parser in a single pass.
+// It returns the ROM once every forward reference is resolved.
+func (a *Assembler) assembleStream(ctx context.Context, p *Parser) ([]uint16, error) {
+	a.reset()
+	errs := errorList{max: a.config.maxErrors}
+	s := &stream{references: make(map[string]*forwardReference)}
+
+	for statements := 1; p.Advance(); statements++ {
+		if statements%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		node, err := p.Node()
+		if err != nil {
+			if errs.add(err) {
+				return nil, errs.err()
 			}
-		case LCommand:
 			continue
 		}
-		if binary == "" {
-			return fmt.Errorf("could not assemble binary with %s: %w", a.parser.Command(), ErrInvalidCommand)
+
+		if a.assembleStreamNode(s, node, &errs) {
+			return nil, errs.err()
 		}
-		_, err := a.w.Write([]byte(binary + "\n"))
-		if err != nil {
-			return err
+	}
+	if err := p.Err(); err != nil {
+		errs.add(err)
+		return nil, errs.err()
+	}
+
+	a.allocateVariables(s.order, s.rom, &errs)
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
+	}
+
+	return s.rom, nil
+}
+
+// assembleStreamNode translates the node into the ROM of the stream, and adds its errors to errs.
+// It returns true if the assembly must stop.
+func (a *Assembler) assembleStreamNode(s *stream, node Node, errs *errorList) bool {
+	address := uint(len(s.rom))
+	var word uint16
+	var err error
+
+	switch command := node.(type) {
+	case *Label:
+		if address >= a.config.memoryMap.ROMSize {
+			errs.add(newSourceError(node, "label %s resolves to %d: %w", command.Name, address, ErrROMOverflow))
+			return true
 		}
+		err = a.defineLabel(s, command, address)
+		return err != nil && errs.add(err)
+	case *AInstruction:
+		if command.Symbol != "" && !a.symbolTable.Contains(command.Symbol) {
+			s.addReference(command, address)
+			break
+		}
+		word, err = a.assembleACommand(command)
+	case *CInstruction:
+		word, err = a.assembleCCommand(command)
+	case *Directive:
+		// Directives need the whole program.
+		errs.add(newSourceError(node, "%s: %w", node, ErrUnsupportedDirective))
+		return true
+	default:
+		return false
 	}
-	return nil
+	if err != nil && errs.add(newSourceError(node, "%s: %w", node, err)) {
+		return true
+	}
+
+	if address >= a.config.memoryMap.ROMSize {
+		errs.add(newSourceError(node, "program exceeds %d words: %w", a.config.memoryMap.ROMSize, ErrROMOverflow))
+		return true
+	}
+	s.rom = append(s.rom, word)
+
+	return false
 }
 
-// createSymbolTable function creates a symbol table from the assembly code.
-// It does this by parsing the assembly code and adding symbols to the symbol table
-// as they are encountered.
-func (a *Assembler) createSymbolTable() error {
-	commands := ""
+// defineLabel adds the label to the symbol table and backpatches the forward references to it.
+func (a *Assembler) defineLabel(s *stream, label *Label, address uint) error {
+	err := a.symbolTable.AddEntry(label.Name, address)
+	if err != nil {
+		return newSourceError(label, "%s: %w", label, err)
+	}
 
-	for a.parser.Advance() {
-		commands += a.parser.Command() + "\n"
+	if reference, ok := s.references[label.Name]; ok {
+		for _, i := range reference.addresses {
+			s.rom[i] = uint16(address)
+		}
+		reference.resolved = true
+		delete(s.references, label.Name)
+	}
 
-		switch a.parser.CommandType() {
-		case LCommand:
-			symbol, err := a.parser.Symbol()
-			if err != nil {
-				return err
-			}
-			err = a.symbolTable.AddEntry(symbol, a.parser.LineNumber())
-			if err != nil {
-				return err
+	return nil
+}
+
+// allocateVariables allocates the symbols never defined as labels as variables,
+// in the order of their first use, and backpatches the references to them.
+func (a *Assembler) allocateVariables(order []*forwardReference, rom []uint16, errs *errorList) {
+	for _, reference := range order {
+		if reference.resolved {
+			continue
+		}
+
+		word, err := a.assembleACommand(reference.first)
+		if err != nil {
+			if errs.add(newSourceError(reference.first, "%s: %w", reference.first, err)) {
+				return
 			}
-		case ACommand:
-		case CCommand:
 			continue
 		}
+		for _, i := range reference.addresses {
+			rom[i] = word
+		}
 	}
-
-	a.parser.Reset(strings.NewReader(commands))
-
-	return nil
 }
@@ -2,10 +2,15 @@ package hack
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
 	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 const (
@@ -279,6 +284,17 @@ const (
 `
 )
 
+const (
+	compactMaxCommands = `
+// Max.asm written with several statements per line.
+@R0;;D=M;;@R1;;D=D-M
+@ITSR0;;D;JGT
+@R1;;D=M;;@R2;;M=D;;@END;;0;JMP
+(ITSR0) @R0;;D=M;;@R2;;M=D
+(END) @END;;0;JMP
+`
+)
+
 func TestAssembler_Assemble(t *testing.T) {
 	t.Parallel()
 
@@ -312,6 +328,11 @@ func TestAssembler_Assemble(t *testing.T) {
 			asm:      rectCommands,
 			binary:   rectCommandsBinary,
 		},
+		{
+			testCase: "compact max",
+			asm:      compactMaxCommands,
+			binary:   maxCommandsBinary,
+		},
 	}
 
 	for _, d := range data {
@@ -337,3 +358,313 @@ func TestAssembler_Assemble(t *testing.T) {
 		})
 	}
 }
+
+func TestAssembler_AssembleSinglePass(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+	}{
+		{"add", noSymbolAddCommands},
+		{"max", maxCommands},
+		{"rect", rectCommands},
+		{"compact max", compactMaxCommands},
+		{"forward references", "@a\nM=0\n@LOOP\n0;JMP\n@b\nM=1\n@a\nD=M\n(LOOP)\n@c\nM=D\n@LOOP\n0;JMP\n"},
+		{"variables interleaved with labels", "@x\n@L1\n@y\n(L1)\n@L2\n@z\n@x\n(L2)\n@y\n"},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			twoPass := &bytes.Buffer{}
+			assembler, err := NewAssembler(strings.NewReader(d.asm), twoPass)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assembler.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The reader hides the Seek method of strings.Reader.
+			singlePass := &bytes.Buffer{}
+			assembler, err = NewAssembler(iotest.OneByteReader(strings.NewReader(d.asm)), singlePass)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assembler.AssembleSinglePass()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(singlePass.String(), twoPass.String()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_ErrorPosition(t *testing.T) {
+	t.Parallel()
+
+	assembler, err := NewAssembler(strings.NewReader("@i\n@j;;D=X+1\n"), &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) {
+		t.Fatalf("expected SourceError, got %v", err)
+	}
+
+	if diff := cmp.Diff([]uint{sourceErr.Line, sourceErr.Column}, []uint{2, 5}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAssembler_Assemble_SyntaxErrorPartial(t *testing.T) {
+	t.Parallel()
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader("@END\nD=A\nD=X\n(END)\n@END\n0;JMP\n"), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if !errors.Is(err, ErrInvalidCompCommand) {
+		t.Errorf("expected ErrInvalidCompCommand, got %v", err)
+	}
+
+	// The label defined after the invalid line is resolved.
+	if diff := cmp.Diff(writer.String(), "0000000000000010\n1110110000010000\n"); diff != "" {
+		t.Error(diff)
+	}
+	if assembler.Size() != 2 {
+		t.Errorf("expected size 2, got %d", assembler.Size())
+	}
+}
+
+func TestAssembler_Assemble_MemoryMapLimits(t *testing.T) {
+	t.Parallel()
+
+	// Variables are allocated from 16, so 17 and 18 are screen and 19 is the keyboard.
+	small := MemoryMap{ROMSize: 5, RAMSize: 20, ScreenAddress: 17, KBDAddress: 19}
+
+	data := []struct {
+		testCase string
+		asm      string
+		err      error
+		warnings int
+	}{
+		{
+			testCase: "fits",
+			asm:      "@a\n(END)\n@END\n0;JMP\n",
+		},
+		{
+			testCase: "ROM overflow",
+			asm:      "@1\n@2\n@3\n@4\n@5\n@6\n",
+			err:      ErrROMOverflow,
+		},
+		{
+			testCase: "label beyond ROM",
+			asm:      "@1\n@2\n@3\n@4\n@5\n(END)\n",
+			err:      ErrROMOverflow,
+		},
+		{
+			testCase: "constant out of range",
+			asm:      "@32768\n",
+			err:      ErrAddressOutOfRange,
+		},
+		{
+			testCase: "variables in screen and keyboard",
+			asm:      "@a\n@b\n@c\n@d\n",
+			warnings: 3,
+		},
+		{
+			testCase: "RAM overflow",
+			asm:      "@a\n@b\n@c\n@d\n@e\n",
+			err:      ErrRAMOverflow,
+			warnings: 3,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			assembler, err := NewAssemblerWithMemoryMap(strings.NewReader(d.asm), &bytes.Buffer{}, small)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Error(diff)
+			}
+
+			if diff := cmp.Diff(len(assembler.Warnings()), d.warnings); diff != "" {
+				t.Error(diff)
+			}
+
+			assembler, err = NewAssemblerWithMemoryMap(strings.NewReader(d.asm), &bytes.Buffer{}, small)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.AssembleSinglePass()
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Error(diff)
+			}
+
+			if diff := cmp.Diff(len(assembler.Warnings()), d.warnings); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAssembler_SetInstructionSet(t *testing.T) {
+	t.Parallel()
+
+	writer := &bytes.Buffer{}
+
+	assembler, err := NewAssembler(strings.NewReader("D=D<<\nAM=M>>;JMP\nD=D+1\n"), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assembler.SetInstructionSet(NewExtendedALUCode())
+
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(writer.String(), "1010110000010000\n1011000000101111\n1110011111010000\n"); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAssembler_Assemble_InputErrors(t *testing.T) {
+	t.Parallel()
+
+	errRead := errors.New("read failed")
+
+	data := []struct {
+		testCase string
+		r        io.Reader
+		opts     []Option
+		err      error
+		line     uint
+	}{
+		{
+			testCase: "read error",
+			r:        io.MultiReader(strings.NewReader("@1\nD=A\n"), iotest.ErrReader(errRead)),
+			err:      errRead,
+			line:     3,
+		},
+		{
+			testCase: "read error within a line",
+			r:        io.MultiReader(strings.NewReader("@1\n(L"), iotest.ErrReader(errRead)),
+			err:      errRead,
+			line:     2,
+		},
+		{
+			testCase: "line too long",
+			r:        strings.NewReader("@1\n@" + strings.Repeat("1", 10) + "\nD=A\n"),
+			opts:     []Option{WithMaxLineLength(10)},
+			err:      ErrLineTooLong,
+			line:     2,
+		},
+		{
+			testCase: "line too long for the default limit",
+			r:        strings.NewReader("@1\n// " + strings.Repeat("x", DefaultMaxLineLength) + "\nD=A\n"),
+			err:      ErrLineTooLong,
+			line:     2,
+		},
+		{
+			testCase: "file too large",
+			r:        strings.NewReader("@1\nD=A\n@2\n"),
+			opts:     []Option{WithMaxFileSize(7)},
+			err:      ErrFileTooLarge,
+			line:     3,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &bytes.Buffer{}
+			assembler, err := NewAssembler(d.r, writer, d.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if !errors.Is(err, d.err) {
+				t.Fatalf("expected %v, got %v", d.err, err)
+			}
+
+			var sourceErr *SourceError
+			if !errors.As(err, &sourceErr) {
+				t.Fatalf("expected SourceError, got %v", err)
+			}
+			if sourceErr.Line != d.line {
+				t.Errorf("expected line %d, got %d", d.line, sourceErr.Line)
+			}
+			if writer.Len() != 0 {
+				t.Errorf("expected no output, got %q", writer.String())
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_MaxFileSize(t *testing.T) {
+	t.Parallel()
+
+	// A source of exactly the maximum size is accepted.
+	assembler, err := NewAssembler(strings.NewReader("@1\nD=A\n"), io.Discard, WithMaxFileSize(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAssembler_AssembleContext(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	source := strings.Repeat("@1\n", 2*cancelCheckInterval)
+
+	assembler, err := NewAssembler(strings.NewReader(source), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.AssembleContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	assembler, err = NewAssembler(strings.NewReader(source), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.AssembleSinglePassContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
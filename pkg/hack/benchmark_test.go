@@ -0,0 +1,101 @@
+package hack
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// benchmarkSource returns a program of about the given number of lines,
+// mixing comments, labels, variables and C-instructions like VM translator output.
+// Three lines out of four are instructions.
+func benchmarkSource(lines int) string {
+	var b strings.Builder
+
+	for i := 0; i < lines; i += 8 {
+		fmt.Fprintf(&b, "// push constant %d\n", i)
+		fmt.Fprintf(&b, "(L%d)\n", i)
+		fmt.Fprintf(&b, "@%d\n", i%32768)
+		b.WriteString("D=A\n")
+		fmt.Fprintf(&b, "@v%d\n", i%1000)
+		b.WriteString("AM=M+1 // increment\n")
+		fmt.Fprintf(&b, "@L%d\n", i)
+		b.WriteString("D;JGT\n")
+	}
+
+	return b.String()
+}
+
+func reportPerLine(b *testing.B, lines int) {
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*lines), "ns/line")
+}
+
+// BenchmarkParse shows that parsing scales linearly up to millions of lines.
+func BenchmarkParse(b *testing.B) {
+	for _, lines := range []int{1_000, 10_000, 100_000, 1_000_000, 4_000_000} {
+		source := benchmarkSource(lines)
+
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.SetBytes(int64(len(source)))
+
+			for i := 0; i < b.N; i++ {
+				_, err := Parse(strings.NewReader(source))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			reportPerLine(b, lines)
+		})
+	}
+}
+
+// BenchmarkAssembler_Assemble shows that assembling scales linearly up to a full ROM.
+func BenchmarkAssembler_Assemble(b *testing.B) {
+	for _, lines := range []int{1_000, 10_000, 40_000} {
+		source := benchmarkSource(lines)
+
+		b.Run(fmt.Sprintf("lines=%d", lines), func(b *testing.B) {
+			b.SetBytes(int64(len(source)))
+
+			for i := 0; i < b.N; i++ {
+				assembler, err := NewAssembler(strings.NewReader(source), io.Discard)
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				err = assembler.Assemble()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			reportPerLine(b, lines)
+		})
+	}
+}
+
+// BenchmarkSymbolTable shows that symbol lookups do not depend on the size of the table.
+func BenchmarkSymbolTable(b *testing.B) {
+	for _, symbols := range []int{100, 10_000, 1_000_000} {
+		table := NewSymbolTable()
+		for i := 0; i < symbols; i++ {
+			err := table.AddEntry(fmt.Sprintf("s%d", i), uint(i))
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+
+		last := fmt.Sprintf("s%d", symbols-1)
+
+		b.Run(fmt.Sprintf("symbols=%d", symbols), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := table.GetAddress(last)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,393 @@
+package hack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BasicBlock is a run of instructions entered only at its first instruction
+// and left only after its last one.
+type BasicBlock struct {
+	// Start and End are the ROM addresses of the first instruction of the block
+	// and of the instruction following its last one.
+	Start uint `json:"start"`
+	End   uint `json:"end"`
+
+	// Labels are the labels of the first instruction, as named in the symbol table.
+	Labels []string `json:"labels,omitempty"`
+
+	Successors []Edge `json:"successors,omitempty"`
+}
+
+// Edge is a transfer of control to another block.
+type Edge struct {
+	// To is the index of the block.
+	To int `json:"to"`
+
+	// Condition is the jump mnemonic of the jump taking the edge,
+	// prefixed with ! for the fall-through of a conditional jump,
+	// and empty for the fall-through of a block not ending with a jump.
+	Condition string `json:"condition,omitempty"`
+
+	// Computed is true if the jump target is not a constant.
+	// A computed jump has an edge to every block whose address is loaded by an A-instruction,
+	// such as the return addresses of calls.
+	Computed bool `json:"computed,omitempty"`
+}
+
+// ControlFlowGraph is the control flow graph of a program, split into basic blocks.
+type ControlFlowGraph struct {
+	// Instructions are the instructions of the program as assembled, including the routines of
+	// the standard library it uses. A-instructions load the address of their symbol in Value,
+	// and keep their Symbol.
+	Instructions []Instruction
+
+	// Blocks are the basic blocks of the program, ordered by address.
+	// The first block is the entry point of the program.
+	Blocks []BasicBlock
+
+	// programSize is the number of instructions of the program itself, before the library routines.
+	programSize int
+
+	// variables maps the addresses of the variables to their names.
+	variables map[uint16]string
+}
+
+// Block returns the index of the block holding the instruction at the address.
+func (g *ControlFlowGraph) Block(address uint) int {
+	return sort.Search(len(g.Blocks), func(i int) bool {
+		return g.Blocks[i].End > address
+	})
+}
+
+// InProgram returns true if the instruction at the address is an instruction of the program,
+// rather than of a library routine it uses.
+func (g *ControlFlowGraph) InProgram(address uint) bool {
+	return address < uint(g.programSize)
+}
+
+// SourceLine is an instruction of a block, as written in the source.
+type SourceLine struct {
+	Address uint `json:"address"`
+	// Line is the line of the instruction in the source of the program,
+	// or 0 for the instructions of the library routines.
+	Line uint   `json:"line,omitempty"`
+	Text string `json:"text"`
+}
+
+// Lines returns the instructions of the block.
+func (g *ControlFlowGraph) Lines(b int) []SourceLine {
+	block := g.Blocks[b]
+	lines := make([]SourceLine, 0, block.End-block.Start)
+	for address := block.Start; address < block.End; address++ {
+		instruction := g.Instructions[address]
+		line := SourceLine{Address: address, Text: instruction.String()}
+		if g.InProgram(address) {
+			line.Line = instruction.Pos().Line
+		}
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// WriteJSON writes the blocks of the graph as JSON, with the instructions of each block.
+func (g *ControlFlowGraph) WriteJSON(w io.Writer) error {
+	type block struct {
+		BasicBlock
+		Lines []SourceLine `json:"lines"`
+	}
+
+	blocks := make([]block, len(g.Blocks))
+	for i, b := range g.Blocks {
+		blocks[i] = block{BasicBlock: b, Lines: g.Lines(i)}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(struct {
+		Blocks []block `json:"blocks"`
+	}{blocks})
+}
+
+// WriteDOT writes the graph in the DOT language of Graphviz.
+// Blocks are labeled with their labels and their source lines, and edges with their jump conditions.
+// Computed jumps are dashed.
+func (g *ControlFlowGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph cfg {\n")
+	b.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+
+	for i, block := range g.Blocks {
+		var label strings.Builder
+		for _, name := range block.Labels {
+			label.WriteString("(" + dotEscape(name) + ")\\l")
+		}
+		for _, line := range g.Lines(i) {
+			if line.Line > 0 {
+				fmt.Fprintf(&label, "%d: ", line.Line)
+			}
+			label.WriteString(dotEscape(line.Text) + "\\l")
+		}
+		fmt.Fprintf(&b, "\tb%d [label=\"%s\"];\n", i, label.String())
+	}
+
+	for i, block := range g.Blocks {
+		for _, edge := range block.Successors {
+			var attributes []string
+			if edge.Condition != "" {
+				attributes = append(attributes, "label=\""+dotEscape(edge.Condition)+"\"")
+			}
+			if edge.Computed {
+				attributes = append(attributes, "style=dashed")
+			}
+			fmt.Fprintf(&b, "\tb%d -> b%d", i, edge.To)
+			if len(attributes) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(attributes, ", "))
+			}
+			b.WriteString(";\n")
+		}
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dotEscape escapes the text for a quoted DOT string.
+func dotEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text)
+}
+
+// resolvedProgram is a program as assembled.
+type resolvedProgram struct {
+	instructions []Instruction
+
+	// labels are the labels at each address, as named in the symbol table.
+	labels map[uint][]string
+
+	// loadsLabel is true for the A-instructions loading the address of a label.
+	loadsLabel []bool
+
+	// symbols are the symbols of the A-instructions as named in the symbol table, or empty.
+	symbols []string
+
+	// definitions are the definitions of the labels, and the first uses of the variables.
+	definitions map[string]definition
+
+	// programSize is the number of instructions of the program, before the library routines.
+	programSize int
+
+	variables map[uint16]string
+}
+
+// definition is where a symbol is defined.
+type definition struct {
+	Position
+
+	// label is true for labels, and false for variables.
+	label bool
+
+	// library is true if the symbol is defined by a library routine.
+	library bool
+}
+
+// resolve assembles the program without writing it, and returns its instructions with their symbols resolved.
+// The program is not stripped.
+func (a *Assembler) resolve(program *Program) (*resolvedProgram, error) {
+	a.reset()
+	errs := errorList{max: a.config.maxErrors}
+
+	full := a.includeLibrary(program, &errs)
+	a.createSymbolTable(full, &errs)
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
+	}
+
+	r := &resolvedProgram{
+		labels:      make(map[uint][]string),
+		definitions: make(map[string]definition),
+		variables:   make(map[uint16]string),
+	}
+	file := ""
+	for i, node := range full.Nodes {
+		file = nextFile(node, file)
+		address := uint(len(r.instructions))
+		library := i >= len(program.Nodes)
+
+		switch command := node.(type) {
+		case *Label:
+			name := a.namespaces.label(file, command.Name)
+			r.labels[address] = append(r.labels[address], name)
+			r.definitions[name] = definition{Position: command.Position, label: true, library: library}
+		case *AInstruction:
+			qualified := a.qualify(command, file)
+			isVariable := qualified.Symbol != "" && !a.symbolTable.Contains(qualified.Symbol)
+			isLabel := qualified.Symbol != "" && !isVariable && !a.config.predefined.Contains(qualified.Symbol)
+
+			word, err := a.assembleACommand(qualified)
+			if err != nil && errs.add(newSourceError(node, "%s: %w", node, err)) {
+				return nil, errs.err()
+			}
+			if isVariable {
+				r.variables[word] = qualified.Symbol
+				r.definitions[qualified.Symbol] = definition{Position: command.Position, library: library}
+			}
+
+			resolved := &AInstruction{Position: command.Position, Value: uint(word), Symbol: command.Symbol}
+			r.instructions = append(r.instructions, resolved)
+			r.loadsLabel = append(r.loadsLabel, isLabel)
+			r.symbols = append(r.symbols, qualified.Symbol)
+		case *CInstruction:
+			r.instructions = append(r.instructions, command)
+			r.loadsLabel = append(r.loadsLabel, false)
+			r.symbols = append(r.symbols, "")
+		}
+		if i == len(program.Nodes)-1 {
+			r.programSize = len(r.instructions)
+		}
+	}
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
+	}
+
+	return r, nil
+}
+
+// BuildCFG assembles the program without writing it, and splits it into basic blocks.
+// Blocks start at the entry point, at labels, at constant jump targets and after jumps.
+// The target of a jump is the address loaded by the A-instruction right before it in the same block,
+// or else is computed.
+func (c *Config) BuildCFG(program *Program) (*ControlFlowGraph, error) {
+	r, err := c.NewAssembler(nil, io.Discard).resolve(program)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildCFG(r), nil
+}
+
+// jumpTarget returns the constant target of the jump at the address, if the A-instruction
+// before it in the same block loads one.
+func jumpTarget(instructions []Instruction, leaders map[uint]bool, address uint) (uint, bool) {
+	if address == 0 || leaders[address] {
+		return 0, false
+	}
+
+	a, ok := instructions[address-1].(*AInstruction)
+	if !ok {
+		return 0, false
+	}
+
+	return a.Value, true
+}
+
+func buildCFG(r *resolvedProgram) *ControlFlowGraph {
+	g := &ControlFlowGraph{Instructions: r.instructions, programSize: r.programSize, variables: r.variables}
+	size := uint(len(r.instructions))
+	if size == 0 {
+		return g
+	}
+
+	leaders := findLeaders(r)
+	starts := make([]uint, 0, len(leaders))
+	for address := range leaders {
+		starts = append(starts, address)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	for i, start := range starts {
+		end := size
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		g.Blocks = append(g.Blocks, BasicBlock{Start: start, End: end, Labels: r.labels[start]})
+	}
+
+	loaded := g.loadedBlocks(r)
+	for i := range g.Blocks {
+		g.Blocks[i].Successors = g.successors(i, leaders, loaded)
+	}
+
+	return g
+}
+
+// findLeaders returns the addresses of the first instructions of the blocks of the program.
+func findLeaders(r *resolvedProgram) map[uint]bool {
+	size := uint(len(r.instructions))
+	leaders := map[uint]bool{0: true}
+	for address := range r.labels {
+		if address < size {
+			leaders[address] = true
+		}
+	}
+	for address, instruction := range r.instructions {
+		if c, ok := instruction.(*CInstruction); ok && c.Jump != "" && uint(address)+1 < size {
+			leaders[uint(address)+1] = true
+		}
+	}
+
+	// jumpTarget needs the leaders following the jumps.
+	for address, instruction := range r.instructions {
+		if c, ok := instruction.(*CInstruction); ok && c.Jump != "" {
+			if target, ok := jumpTarget(r.instructions, leaders, uint(address)); ok && target < size {
+				leaders[target] = true
+			}
+		}
+	}
+
+	return leaders
+}
+
+// loadedBlocks returns the blocks whose address is loaded, which may be the targets of computed jumps.
+func (g *ControlFlowGraph) loadedBlocks(r *resolvedProgram) []int {
+	var loaded []int
+	seen := make(map[int]bool)
+	for address, instruction := range r.instructions {
+		if a, ok := instruction.(*AInstruction); ok && r.loadsLabel[address] && a.Value < uint(len(r.instructions)) {
+			if b := g.Block(a.Value); !seen[b] {
+				seen[b] = true
+				loaded = append(loaded, b)
+			}
+		}
+	}
+	sort.Ints(loaded)
+
+	return loaded
+}
+
+// successors returns the edges leaving the block.
+func (g *ControlFlowGraph) successors(i int, leaders map[uint]bool, loaded []int) []Edge {
+	block := g.Blocks[i]
+	last := block.End - 1
+	next := i + 1
+	hasNext := next < len(g.Blocks)
+
+	command, ok := g.Instructions[last].(*CInstruction)
+	if !ok || command.Jump == "" {
+		if hasNext {
+			return []Edge{{To: next}}
+		}
+		return nil
+	}
+
+	var edges []Edge
+	if target, ok := jumpTarget(g.Instructions, leaders, last); ok {
+		if target < uint(len(g.Instructions)) {
+			edges = append(edges, Edge{To: g.Block(target), Condition: command.Jump})
+		}
+	} else {
+		for _, b := range loaded {
+			edges = append(edges, Edge{To: b, Condition: command.Jump, Computed: true})
+		}
+	}
+
+	if !isUnconditionalJump(command) && hasNext {
+		edges = append(edges, Edge{To: next, Condition: "!" + command.Jump})
+	}
+
+	return edges
+}
@@ -0,0 +1,220 @@
+package hack
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// cfgSource builds the control flow graph of the source with the standard configuration.
+func cfgSource(t *testing.T, source string) *ControlFlowGraph {
+	t.Helper()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := config.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := config.BuildCFG(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return g
+}
+
+func TestConfig_BuildCFG(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		want     []BasicBlock
+	}{
+		{
+			"empty", "// nothing\n", nil,
+		},
+		{
+			"straight", "@1\nD=A\n@R0\nM=D\n",
+			[]BasicBlock{{Start: 0, End: 4}},
+		},
+		{
+			"loop",
+			"@R0\nD=M\n(LOOP)\n@END\nD;JEQ\nD=D-1\n@LOOP\n0;JMP\n(END)\n@END\n0;JMP\n",
+			[]BasicBlock{
+				{Start: 0, End: 2, Successors: []Edge{{To: 1}}},
+				{Start: 2, End: 4, Labels: []string{"LOOP"}, Successors: []Edge{{To: 3, Condition: "JEQ"}, {To: 2, Condition: "!JEQ"}}},
+				{Start: 4, End: 7, Successors: []Edge{{To: 1, Condition: "JMP"}}},
+				{Start: 7, End: 9, Labels: []string{"END"}, Successors: []Edge{{To: 3, Condition: "JMP"}}},
+			},
+		},
+		{
+			"constant target",
+			"@3\n0;JMP\nD=0\nD=1\n",
+			[]BasicBlock{
+				{Start: 0, End: 2, Successors: []Edge{{To: 2, Condition: "JMP"}}},
+				{Start: 2, End: 3, Successors: []Edge{{To: 2}}},
+				{Start: 3, End: 4},
+			},
+		},
+		{
+			"computed jump",
+			"@RET\nD=A\n@F\n0;JMP\n(RET)\n@RET\n0;JMP\n(F)\n@R13\nM=D\n@R13\nA=M\n0;JMP\n",
+			[]BasicBlock{
+				{Start: 0, End: 4, Successors: []Edge{{To: 2, Condition: "JMP"}}},
+				{Start: 4, End: 6, Labels: []string{"RET"}, Successors: []Edge{{To: 1, Condition: "JMP"}}},
+				{Start: 6, End: 11, Labels: []string{"F"}, Successors: []Edge{
+					{To: 1, Condition: "JMP", Computed: true},
+					{To: 2, Condition: "JMP", Computed: true},
+				}},
+			},
+		},
+		{
+			"private label",
+			".file Main\n(LOOP)\n@LOOP\n0;JMP\n",
+			[]BasicBlock{{Start: 0, End: 2, Labels: []string{"Main.LOOP"}, Successors: []Edge{{To: 0, Condition: "JMP"}}}},
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(cfgSource(t, d.source).Blocks, d.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestControlFlowGraph_Block(t *testing.T) {
+	t.Parallel()
+
+	g := cfgSource(t, "@R0\nD=M\n(LOOP)\n@END\nD;JEQ\nD=D-1\n@LOOP\n0;JMP\n(END)\n@END\n0;JMP\n")
+	for address, want := range []int{0, 0, 1, 1, 2, 2, 2, 3, 3} {
+		if got := g.Block(uint(address)); got != want {
+			t.Errorf("Block(%d) = %d, want %d", address, got, want)
+		}
+	}
+}
+
+func TestConfig_BuildCFG_Library(t *testing.T) {
+	t.Parallel()
+
+	g := cfgSource(t, ".use mult\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n@RET\n0;JMP\n")
+	if g.programSize != 6 || len(g.Instructions) <= g.programSize {
+		t.Fatalf("got %d instructions with %d of the program", len(g.Instructions), g.programSize)
+	}
+
+	entry := g.Blocks[g.Block(uint(g.programSize))]
+	if diff := cmp.Diff(entry.Labels, []string{"std.mult"}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfig_BuildCFG_Errors(t *testing.T) {
+	t.Parallel()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := config.Parse(strings.NewReader("(L)\n(L)\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = config.BuildCFG(program)
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) || sourceErr.Line != 2 {
+		t.Errorf("got %v, want an error at line 2", err)
+	}
+}
+
+func TestControlFlowGraph_WriteDOT(t *testing.T) {
+	t.Parallel()
+
+	g := cfgSource(t, "@R0\nD=M\n(LOOP)\n@END\nD;JEQ\nD=D-1\n@LOOP\n0;JMP\n(END)\n@END\n0;JMP\n")
+
+	var b strings.Builder
+	err := g.WriteDOT(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `digraph cfg {
+	node [shape=box, fontname="monospace"];
+	b0 [label="1: @R0\l2: D=M\l"];
+	b1 [label="(LOOP)\l4: @END\l5: D;JEQ\l"];
+	b2 [label="6: D=D-1\l7: @LOOP\l8: 0;JMP\l"];
+	b3 [label="(END)\l10: @END\l11: 0;JMP\l"];
+	b0 -> b1;
+	b1 -> b3 [label="JEQ"];
+	b1 -> b2 [label="!JEQ"];
+	b2 -> b1 [label="JMP"];
+	b3 -> b3 [label="JMP"];
+}
+`
+	if diff := cmp.Diff(b.String(), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestControlFlowGraph_WriteJSON(t *testing.T) {
+	t.Parallel()
+
+	g := cfgSource(t, "@RET\nD=A\n@F\n0;JMP\n(RET)\n@RET\n0;JMP\n(F)\n@R13\nA=D\nD;JMP\n")
+
+	var b strings.Builder
+	err := g.WriteJSON(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got struct {
+		Blocks []struct {
+			BasicBlock
+			Lines []SourceLine `json:"lines"`
+		} `json:"blocks"`
+	}
+	err = json.Unmarshal([]byte(b.String()), &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.Blocks) != len(g.Blocks) {
+		t.Fatalf("got %d blocks, want %d", len(got.Blocks), len(g.Blocks))
+	}
+	for i, block := range got.Blocks {
+		if diff := cmp.Diff(block.BasicBlock, g.Blocks[i]); diff != "" {
+			t.Error(diff)
+		}
+		if diff := cmp.Diff(block.Lines, g.Lines(i)); diff != "" {
+			t.Error(diff)
+		}
+	}
+	if !strings.Contains(b.String(), `"computed": true`) {
+		t.Errorf("computed edges not written:\n%s", b.String())
+	}
+}
+
+func TestControlFlowGraph_Lines_Library(t *testing.T) {
+	t.Parallel()
+
+	g := cfgSource(t, ".use mult\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n@RET\n0;JMP\n")
+
+	lines := g.Lines(len(g.Blocks) - 1)
+	if lines[0].Line != 0 {
+		t.Errorf("library line %d, want 0", lines[0].Line)
+	}
+	if lines := g.Lines(0); lines[0].Line != 2 || lines[0].Text != "@RET" {
+		t.Errorf("got %+v, want @RET at line 2", lines[0])
+	}
+}
@@ -1,167 +1,384 @@
 package hack
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"strconv"
 )
 
-// Code is a struct that translates Hack assembly language mnemonics into binary codes.
-type Code struct{}
+// InstructionSet translates the mnemonics of C-instructions into binary codes.
+// The Assembler and the Parser consult it to recognize and encode C-instructions.
+// The codes are the values of the bit fields, not shifted to their positions in the instruction.
+type InstructionSet interface {
+	// Dest returns the 3 dest bits of the dest mnemonic.
+	Dest(n string) (uint16, error)
+	// Comp returns the a-bit and the 6 comp bits of the comp mnemonic.
+	Comp(n string) (uint16, error)
+	// Jump returns the 3 jump bits of the jump mnemonic.
+	Jump(n string) (uint16, error)
+	// Prefix returns the 3 high bits of a C-instruction computing the comp mnemonic.
+	Prefix(comp string) (uint16, error)
+}
 
-// NewCode returns a new Code.
-func NewCode() Code {
-	return Code{}
+// InstructionDecoder translates the machine code of C-instructions back into mnemonics.
+type InstructionDecoder interface {
+	// Mnemonics returns the dest, comp and jump mnemonics of a C-instruction.
+	Mnemonics(word uint16) (dest string, comp string, jump string, err error)
 }
 
-// ErrInvalidNemonic is returned when the nemonic is invalid.
-var ErrInvalidNemonic = errors.New("invalid nemonic")
+// Names of the built-in instruction sets.
+const (
+	// StandardInstructionSetName is the instruction set of the nand2tetris Hack CPU.
+	StandardInstructionSetName = "standard"
+	// ExtendedALUInstructionSetName is the Hack CPU whose ALU can also shift D, A and M
+	// by one bit. Shift instructions are encoded with the 101 prefix.
+	ExtendedALUInstructionSetName = "extended"
+	// EmptyInstructionSetName is an instruction set without any mnemonic,
+	// to be used as the base of custom instruction sets.
+	EmptyInstructionSetName = "none"
+)
 
-// Dest returns the binary code of the dest mnemonic.
-func (c Code) Dest(n string) (string, error) {
-	if n == "" {
-		return "000", nil
-	}
-	if n == "M" {
-		return "001", nil
-	}
-	if n == "D" {
-		return "010", nil
-	}
-	if n == "MD" {
-		return "011", nil
-	}
-	if n == "A" {
-		return "100", nil
-	}
-	if n == "AM" {
-		return "101", nil
-	}
-	if n == "AD" {
-		return "110", nil
-	}
-	if n == "AMD" {
-		return "111", nil
-	}
+// Widths of the fields of a C-instruction, in bits.
+const (
+	prefixWidth = 3
+	compWidth   = 7
+	destWidth   = 3
+	jumpWidth   = 3
+)
 
-	return "", fmt.Errorf("could not convert a dest command:%s: %w", n, ErrInvalidNemonic)
+// Code is a table-driven InstructionSet that translates Hack assembly language
+// mnemonics into binary codes.
+type Code struct {
+	prefix   uint16
+	prefixes map[string]uint16
+	dest     map[string]uint16
+	comp     map[string]uint16
+	jump     map[string]uint16
 }
 
-// Comp returns the binary code of the comp mnemonic.
-func (c Code) Comp(n string) (string, error) {
-	if n == "0" {
-		return "0101010", nil
+var (
+	standardDest = map[string]uint16{
+		"":    0b000,
+		"M":   0b001,
+		"D":   0b010,
+		"MD":  0b011,
+		"A":   0b100,
+		"AM":  0b101,
+		"AD":  0b110,
+		"AMD": 0b111,
 	}
-	if n == "1" {
-		return "0111111", nil
-	}
-	if n == "-1" {
-		return "0111010", nil
-	}
-	if n == "D" {
-		return "0001100", nil
-	}
-	if n == "A" {
-		return "0110000", nil
-	}
-	if n == "!D" {
-		return "0001101", nil
+
+	standardComp = map[string]uint16{
+		"0":   0b0101010,
+		"1":   0b0111111,
+		"-1":  0b0111010,
+		"D":   0b0001100,
+		"A":   0b0110000,
+		"!D":  0b0001101,
+		"!A":  0b0110001,
+		"-D":  0b0001111,
+		"-A":  0b0110011,
+		"D+1": 0b0011111,
+		"A+1": 0b0110111,
+		"D-1": 0b0001110,
+		"A-1": 0b0110010,
+		"D+A": 0b0000010,
+		"D-A": 0b0010011,
+		"A-D": 0b0000111,
+		"D&A": 0b0000000,
+		"D|A": 0b0010101,
+		"M":   0b1110000,
+		"!M":  0b1110001,
+		"-M":  0b1110011,
+		"M+1": 0b1110111,
+		"M-1": 0b1110010,
+		"D+M": 0b1000010,
+		"D-M": 0b1010011,
+		"M-D": 0b1000111,
+		"D&M": 0b1000000,
+		"D|M": 0b1010101,
 	}
-	if n == "!A" {
-		return "0110001", nil
+
+	standardJump = map[string]uint16{
+		"":    0b000,
+		"JGT": 0b001,
+		"JEQ": 0b010,
+		"JGE": 0b011,
+		"JLT": 0b100,
+		"JNE": 0b101,
+		"JLE": 0b110,
+		"JMP": 0b111,
 	}
-	if n == "-D" {
-		return "0001111", nil
+
+	extendedALUComp = map[string]uint16{
+		"D<<": 0b0110000,
+		"A<<": 0b0100000,
+		"M<<": 0b1100000,
+		"D>>": 0b0010000,
+		"A>>": 0b0000000,
+		"M>>": 0b1000000,
 	}
-	if n == "-A" {
-		return "0110011", nil
+
+	extendedALUPrefix uint16 = 0b101
+)
+
+const standardPrefix uint16 = 0b111
+
+func newEmptyCode() Code {
+	return Code{
+		prefix:   standardPrefix,
+		prefixes: map[string]uint16{},
+		dest:     map[string]uint16{},
+		comp:     map[string]uint16{},
+		jump:     map[string]uint16{},
 	}
-	if n == "D+1" {
-		return "0011111", nil
+}
+
+// extend adds the mnemonics to the code.
+// The comps are computed by C-instructions with the given prefix,
+// or with the default prefix of the code if prefix is nil.
+func (c Code) extend(prefix *uint16, dest, comp, jump map[string]uint16) {
+	for n, bits := range dest {
+		c.dest[n] = bits
+	}
+	for n, bits := range comp {
+		c.comp[n] = bits
+		if prefix != nil {
+			c.prefixes[n] = *prefix
+		}
+	}
+	for n, bits := range jump {
+		c.jump[n] = bits
 	}
-	if n == "A+1" {
-		return "0110111", nil
+}
+
+// NewCode returns a new Code of the standard Hack instruction set.
+func NewCode() Code {
+	c := newEmptyCode()
+	c.extend(nil, standardDest, standardComp, standardJump)
+	return c
+}
+
+// NewExtendedALUCode returns a new Code of the extended ALU instruction set.
+// It adds the D<<, A<<, M<<, D>>, A>> and M>> shift comps to the standard instruction set.
+func NewExtendedALUCode() Code {
+	c := NewCode()
+	c.extend(&extendedALUPrefix, nil, extendedALUComp, nil)
+	return c
+}
+
+// ErrUnknownInstructionSet is returned when a name is not a built-in instruction set.
+var ErrUnknownInstructionSet = errors.New("unknown instruction set")
+
+// LookupInstructionSet returns the built-in instruction set with the given name.
+func LookupInstructionSet(name string) (Code, error) {
+	switch name {
+	case StandardInstructionSetName:
+		return NewCode(), nil
+	case ExtendedALUInstructionSetName:
+		return NewExtendedALUCode(), nil
+	case EmptyInstructionSetName:
+		return newEmptyCode(), nil
 	}
-	if n == "D-1" {
-		return "0001110", nil
+
+	return Code{}, fmt.Errorf("%s: %w", name, ErrUnknownInstructionSet)
+}
+
+// ErrInvalidInstructionSet is returned when an instruction set can not be loaded.
+var ErrInvalidInstructionSet = errors.New("invalid instruction set")
+
+func parseBits(kind string, n string, bits string, width int) (uint16, error) {
+	if len(bits) != width {
+		return 0, fmt.Errorf("%s %s must be %d bits: %s: %w", kind, n, width, bits, ErrInvalidInstructionSet)
 	}
-	if n == "A-1" {
-		return "0110010", nil
+
+	value, err := strconv.ParseUint(bits, 2, width)
+	if err != nil {
+		return 0, fmt.Errorf("%s %s must be %d bits: %s: %w", kind, n, width, bits, ErrInvalidInstructionSet)
 	}
-	if n == "D+A" {
-		return "0000010", nil
+
+	return uint16(value), nil
+}
+
+func parseBitsTable(kind string, table map[string]string, width int) (map[string]uint16, error) {
+	values := make(map[string]uint16, len(table))
+	for n, bits := range table {
+		value, err := parseBits(kind, n, bits, width)
+		if err != nil {
+			return nil, err
+		}
+		values[n] = value
 	}
-	if n == "D-A" {
-		return "0010011", nil
+
+	return values, nil
+}
+
+// LoadCode reads a JSON encoding table such as:
+//
+//	{
+//	  "base": "standard",
+//	  "comp": {"D*2": "0011000"},
+//	  "prefixes": {"D*2": "100"}
+//	}
+//
+// The mnemonics of dest, comp and jump extend or override the base instruction set,
+// and prefixes set the high bits of the C-instructions computing the given comps,
+// which default to prefix.
+// An omitted base defaults to the standard instruction set.
+func LoadCode(r io.Reader) (Code, error) {
+	var table struct {
+		Base     string            `json:"base"`
+		Prefix   string            `json:"prefix"`
+		Prefixes map[string]string `json:"prefixes"`
+		Dest     map[string]string `json:"dest"`
+		Comp     map[string]string `json:"comp"`
+		Jump     map[string]string `json:"jump"`
 	}
-	if n == "A-D" {
-		return "0000111", nil
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&table)
+	if err != nil {
+		return Code{}, fmt.Errorf("could not decode instruction set: %s: %w", err.Error(), ErrInvalidInstructionSet)
 	}
-	if n == "D&A" {
-		return "0000000", nil
+
+	if table.Base == "" {
+		table.Base = StandardInstructionSetName
 	}
-	if n == "D|A" {
-		return "0010101", nil
+
+	c, err := LookupInstructionSet(table.Base)
+	if err != nil {
+		return c, fmt.Errorf("%w: %w", err, ErrInvalidInstructionSet)
 	}
-	if n == "M" {
-		return "1110000", nil
+
+	if table.Prefix != "" {
+		c.prefix, err = parseBits("prefix", "", table.Prefix, prefixWidth)
+		if err != nil {
+			return c, err
+		}
 	}
-	if n == "!M" {
-		return "1110001", nil
+
+	prefixes, err := parseBitsTable("prefix", table.Prefixes, prefixWidth)
+	if err != nil {
+		return c, err
 	}
-	if n == "-M" {
-		return "1110011", nil
+	dest, err := parseBitsTable("dest", table.Dest, destWidth)
+	if err != nil {
+		return c, err
 	}
-	if n == "M+1" {
-		return "1110111", nil
+	comp, err := parseBitsTable("comp", table.Comp, compWidth)
+	if err != nil {
+		return c, err
 	}
-	if n == "M-1" {
-		return "1110010", nil
+	jump, err := parseBitsTable("jump", table.Jump, jumpWidth)
+	if err != nil {
+		return c, err
 	}
-	if n == "D+M" {
-		return "1000010", nil
+
+	c.extend(nil, dest, comp, jump)
+	for n, bits := range prefixes {
+		c.prefixes[n] = bits
 	}
-	if n == "D-M" {
-		return "1010011", nil
+
+	if _, ok := c.dest[""]; !ok {
+		return c, fmt.Errorf("dest must encode an omitted dest: %w", ErrInvalidInstructionSet)
 	}
-	if n == "M-D" {
-		return "1000111", nil
+	if _, ok := c.jump[""]; !ok {
+		return c, fmt.Errorf("jump must encode an omitted jump: %w", ErrInvalidInstructionSet)
 	}
-	if n == "D&M" {
-		return "1000000", nil
+
+	return c, nil
+}
+
+// ErrInvalidNemonic is returned when the nemonic is invalid.
+var ErrInvalidNemonic = errors.New("invalid nemonic")
+
+// Dest returns the binary code of the dest mnemonic.
+func (c Code) Dest(n string) (uint16, error) {
+	if bits, ok := c.dest[n]; ok {
+		return bits, nil
 	}
-	if n == "D|M" {
-		return "1010101", nil
+
+	return 0, fmt.Errorf("could not convert a dest command:%s: %w", n, ErrInvalidNemonic)
+}
+
+// Comp returns the binary code of the comp mnemonic.
+func (c Code) Comp(n string) (uint16, error) {
+	if bits, ok := c.comp[n]; ok {
+		return bits, nil
 	}
 
-	return "", fmt.Errorf("could not convert a comp command:%s: %w", n, ErrInvalidNemonic)
+	return 0, fmt.Errorf("could not convert a comp command:%s: %w", n, ErrInvalidNemonic)
 }
 
 // Jump returns the binary code of the jump mnemonic.
-func (c Code) Jump(n string) (string, error) {
-	if n == "" {
-		return "000", nil
+func (c Code) Jump(n string) (uint16, error) {
+	if bits, ok := c.jump[n]; ok {
+		return bits, nil
 	}
-	if n == "JGT" {
-		return "001", nil
-	}
-	if n == "JEQ" {
-		return "010", nil
+
+	return 0, fmt.Errorf("could not convert a jump command:%s: %w", n, ErrInvalidNemonic)
+}
+
+func (c Code) prefixOf(comp string) (uint16, error) {
+	if _, ok := c.comp[comp]; !ok {
+		return 0, fmt.Errorf("could not convert a comp command:%s: %w", comp, ErrInvalidNemonic)
 	}
-	if n == "JGE" {
-		return "011", nil
+
+	if bits, ok := c.prefixes[comp]; ok {
+		return bits, nil
 	}
-	if n == "JLT" {
-		return "100", nil
+
+	return c.prefix, nil
+}
+
+// Prefix returns the high bits of a C-instruction computing the comp mnemonic.
+func (c Code) Prefix(comp string) (uint16, error) {
+	return c.prefixOf(comp)
+}
+
+func lookupMnemonic(table map[string]uint16, bits uint16, match func(n string) bool) (string, bool) {
+	found := false
+	mnemonic := ""
+	for n, b := range table {
+		if b != bits || !match(n) {
+			continue
+		}
+		// Several mnemonics may share a code, so pick one deterministically.
+		if !found || n < mnemonic {
+			mnemonic = n
+		}
+		found = true
 	}
-	if n == "JNE" {
-		return "101", nil
+
+	return mnemonic, found
+}
+
+// Mnemonics returns the dest, comp and jump mnemonics of a C-instruction.
+func (c Code) Mnemonics(word uint16) (string, string, string, error) {
+	prefix := (word & PrefixMask) >> PrefixShift
+
+	comp, ok := lookupMnemonic(c.comp, (word&CompMask)>>CompShift, func(n string) bool {
+		p, err := c.prefixOf(n)
+		return err == nil && p == prefix
+	})
+	if !ok {
+		return "", "", "", fmt.Errorf("could not decode a comp command:%016b: %w", word, ErrInvalidNemonic)
 	}
-	if n == "JLE" {
-		return "110", nil
+
+	anyMnemonic := func(string) bool { return true }
+
+	dest, ok := lookupMnemonic(c.dest, (word&DestMask)>>DestShift, anyMnemonic)
+	if !ok {
+		return "", "", "", fmt.Errorf("could not decode a dest command:%016b: %w", word, ErrInvalidNemonic)
 	}
-	if n == "JMP" {
-		return "111", nil
+
+	jump, ok := lookupMnemonic(c.jump, word&JumpMask, anyMnemonic)
+	if !ok {
+		return "", "", "", fmt.Errorf("could not decode a jump command:%016b: %w", word, ErrInvalidNemonic)
 	}
 
-	return "", fmt.Errorf("could not convert a jump command:%s: %w", n, ErrInvalidNemonic)
+	return dest, comp, jump, nil
 }
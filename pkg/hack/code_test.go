@@ -1,9 +1,11 @@
 package hack
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 )
 
 func TestCode_Dest(t *testing.T) {
@@ -15,7 +17,7 @@ func TestCode_Dest(t *testing.T) {
 		t.Error(err)
 	}
 
-	if diff := cmp.Diff(bits, "001"); diff != "" {
+	if diff := cmp.Diff(bits, uint16(0b001)); diff != "" {
 		t.Error(diff)
 	}
 }
@@ -29,7 +31,7 @@ func TestCode_Comp(t *testing.T) {
 		t.Error(err)
 	}
 
-	if diff := cmp.Diff(bits, "0011111"); diff != "" {
+	if diff := cmp.Diff(bits, uint16(0b0011111)); diff != "" {
 		t.Error(diff)
 	}
 }
@@ -43,7 +45,136 @@ func TestCode_Jump(t *testing.T) {
 		t.Error(err)
 	}
 
-	if diff := cmp.Diff(bits, "001"); diff != "" {
+	if diff := cmp.Diff(bits, uint16(0b001)); diff != "" {
 		t.Error(diff)
 	}
 }
+
+func TestCode_Prefix(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		code     Code
+		comp     string
+		bits     uint16
+		err      error
+	}{
+		{
+			testCase: "standard",
+			code:     NewCode(),
+			comp:     "D+1",
+			bits:     0b111,
+		},
+		{
+			testCase: "standard has no shift",
+			code:     NewCode(),
+			comp:     "D<<",
+			err:      ErrInvalidNemonic,
+		},
+		{
+			testCase: "extended shift",
+			code:     NewExtendedALUCode(),
+			comp:     "M>>",
+			bits:     0b101,
+		},
+		{
+			testCase: "extended standard comp",
+			code:     NewExtendedALUCode(),
+			comp:     "M",
+			bits:     0b111,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			bits, err := d.code.Prefix(d.comp)
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if diff := cmp.Diff(bits, d.bits); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLoadCode(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		json     string
+		comp     string
+		bits     uint16
+		prefix   uint16
+		err      error
+	}{
+		{
+			testCase: "extends standard",
+			json:     `{"comp": {"D*2": "0011000"}, "prefixes": {"D*2": "100"}}`,
+			comp:     "D*2",
+			bits:     0b0011000,
+			prefix:   0b100,
+		},
+		{
+			testCase: "extends extended",
+			json:     `{"base": "extended", "prefix": "110"}`,
+			comp:     "D+1",
+			bits:     0b0011111,
+			prefix:   0b110,
+		},
+		{
+			testCase: "invalid bits",
+			json:     `{"comp": {"D*2": "001100"}}`,
+			err:      ErrInvalidInstructionSet,
+		},
+		{
+			testCase: "no omitted dest",
+			json:     `{"base": "none", "comp": {"0": "0101010"}, "jump": {"": "000"}}`,
+			err:      ErrInvalidInstructionSet,
+		},
+		{
+			testCase: "unknown base",
+			json:     `{"base": "z80"}`,
+			err:      ErrInvalidInstructionSet,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := LoadCode(strings.NewReader(d.json))
+			if d.err != nil {
+				if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+					t.Fatal(diff)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			bits, err := c.Comp(d.comp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(bits, d.bits); diff != "" {
+				t.Error(diff)
+			}
+
+			prefix, err := c.Prefix(d.comp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(prefix, d.prefix); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package hack
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Config is the configuration shared by the assemblers of a Hack computer:
+// its memory map, its instruction set and its predefined symbols.
+// A Config is immutable once created, so that a single Config can create assemblers
+// for many programs, including concurrently from multiple goroutines.
+// The assemblers share the tables of the Config and keep the labels and the variables
+// of their programs to themselves.
+type Config struct {
+	memoryMap     MemoryMap
+	code          InstructionSet
+	predefined    *SymbolTable
+	variableBase  uint
+	format        Format
+	strict        bool
+	strip         bool
+	maxErrors     int
+	maxLineLength int
+	maxFileSize   int64
+	tabWidth      int
+}
+
+// NewConfig creates a configuration for the standard Hack computer, changed by the options.
+func NewConfig(opts ...Option) (*Config, error) {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	profile := o.profile
+	if o.memoryMap != nil {
+		profile.MemoryMap = *o.memoryMap
+	}
+	if o.symbols != nil {
+		symbols := make(map[string]uint, len(profile.Symbols)+len(o.symbols))
+		for symbol, address := range profile.Symbols {
+			symbols[symbol] = address
+		}
+		for symbol, address := range o.symbols {
+			symbols[symbol] = address
+		}
+		profile.Symbols = symbols
+	}
+
+	err := profile.MemoryMap.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if o.variableBase >= profile.MemoryMap.RAMSize {
+		return nil, fmt.Errorf("variable base %d is beyond the RAM: %w", o.variableBase, ErrInvalidOption)
+	}
+	if o.maxLineLength < 1 {
+		return nil, fmt.Errorf("max line length %d: %w", o.maxLineLength, ErrInvalidOption)
+	}
+	if o.tabWidth < 1 {
+		return nil, fmt.Errorf("tab width %d: %w", o.tabWidth, ErrInvalidOption)
+	}
+	if o.format < FormatBinary || o.format > FormatRaw {
+		return nil, fmt.Errorf("%s: %w", o.format, ErrUnknownFormat)
+	}
+
+	symbols, err := profile.PredefinedSymbols()
+	if err != nil {
+		return nil, err
+	}
+
+	table := NewSymbolTable()
+	for symbol, address := range symbols {
+		err = table.AddEntry(symbol, address)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Config{
+		memoryMap:     profile.MemoryMap,
+		code:          o.code,
+		predefined:    table,
+		variableBase:  o.variableBase,
+		format:        o.format,
+		strict:        o.strict,
+		strip:         o.strip,
+		maxErrors:     o.maxErrors,
+		maxLineLength: o.maxLineLength,
+		maxFileSize:   o.maxFileSize,
+		tabWidth:      o.tabWidth,
+	}, nil
+}
+
+// standardConfig returns the configuration of the standard Hack computer.
+var standardConfig = sync.OnceValues(func() (*Config, error) {
+	return NewConfig()
+})
+
+// MemoryMap returns the memory map of the configuration.
+func (c *Config) MemoryMap() MemoryMap {
+	return c.memoryMap
+}
+
+// IsPredefined returns true if the symbol is predefined by the configuration.
+func (c *Config) IsPredefined(symbol string) bool {
+	return c.predefined.Contains(symbol)
+}
+
+// Parse parses the assembly code read from r into a Program with the instruction set and
+// the limits of the configuration. Comments are kept as Comment nodes.
+func (c *Config) Parse(r io.Reader) (*Program, error) {
+	parser := c.NewAssembler(r, io.Discard).parser
+	parser.keepComments = true
+
+	return parse(context.Background(), parser, c.maxErrors)
+}
+
+// NewAssembler creates an Assembler reading the assembly code from r and
+// writing the machine code to w.
+func (c *Config) NewAssembler(r io.Reader, w io.Writer) *Assembler {
+	parser := NewParser(r)
+	parser.SetInstructionSet(c.code)
+	parser.SetLimits(c.maxLineLength, c.maxFileSize)
+	parser.SetTabWidth(c.tabWidth)
+
+	a := &Assembler{
+		w:      w,
+		parser: parser,
+		config: c,
+		code:   c.code,
+	}
+	a.reset()
+
+	return a
+}
@@ -0,0 +1,81 @@
+package hack
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestConfig_NewAssembler_Concurrent(t *testing.T) {
+	t.Parallel()
+
+	config, err := NewConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	programs := []struct {
+		asm    string
+		binary string
+	}{
+		{maxCommands, maxCommandsBinary},
+		{rectCommands, rectCommandsBinary},
+	}
+
+	var wg sync.WaitGroup
+	outputs := make([]string, 16)
+	for i := range outputs {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			writer := &bytes.Buffer{}
+			err := config.NewAssembler(strings.NewReader(programs[i%len(programs)].asm), writer).Assemble()
+			if err != nil {
+				t.Error(err)
+			}
+			outputs[i] = writer.String()
+		}()
+	}
+	wg.Wait()
+
+	for i, output := range outputs {
+		if diff := cmp.Diff(output, programs[i%len(programs)].binary); diff != "" {
+			t.Error(diff)
+		}
+	}
+
+	if config.predefined.Contains("LOOP") || config.predefined.Contains("ITSR0") {
+		t.Error("labels leaked into the predefined symbols")
+	}
+}
+
+func TestAssembler_Reset(t *testing.T) {
+	t.Parallel()
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader(maxCommands), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The labels of Max.asm would be duplicated if the symbol table was kept.
+	writer = &bytes.Buffer{}
+	assembler.Reset(strings.NewReader(maxCommands), writer)
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(writer.String(), maxCommandsBinary); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,454 @@
+package hack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxValues is the number of values a Value tracks before it holds any value.
+const maxValues = 16
+
+// Value is what is known of the value of a register.
+type Value struct {
+	// Values are the values the register may hold, sorted, unless Any is true.
+	Values []uint16
+	// Any is true if the register may hold any value.
+	Any bool
+	// Undefined is true if the register may not have been assigned yet.
+	Undefined bool
+}
+
+func (v Value) String() string {
+	var s string
+	switch {
+	case v.Any:
+		s = "any"
+	case len(v.Values) == 0:
+		s = "undefined"
+	default:
+		values := make([]string, len(v.Values))
+		for i, value := range v.Values {
+			values[i] = fmt.Sprint(value)
+		}
+		s = "{" + strings.Join(values, ", ") + "}"
+	}
+	if v.Undefined && (v.Any || len(v.Values) > 0) {
+		s += " or undefined"
+	}
+
+	return s
+}
+
+// known returns the values of the register, or false if it may hold any value.
+func (v Value) known() ([]uint16, bool) {
+	return v.Values, !v.Any && len(v.Values) > 0
+}
+
+// join returns the values held by either register.
+func (v Value) join(w Value) Value {
+	joined := Value{Any: v.Any || w.Any, Undefined: v.Undefined || w.Undefined}
+	if joined.Any {
+		return joined
+	}
+
+	joined.Values = unionValues(v.Values, w.Values)
+	if len(joined.Values) > maxValues {
+		joined.Values, joined.Any = nil, true
+	}
+
+	return joined
+}
+
+func (v Value) equal(w Value) bool {
+	if v.Any != w.Any || v.Undefined != w.Undefined || len(v.Values) != len(w.Values) {
+		return false
+	}
+	for i := range v.Values {
+		if v.Values[i] != w.Values[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unionValues returns the sorted union of two sorted sets of values.
+func unionValues(a, b []uint16) []uint16 {
+	union := make([]uint16, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || i < len(a) && a[i] < b[j]:
+			union = append(union, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			union = append(union, b[j])
+			j++
+		default:
+			union = append(union, a[i])
+			i++
+			j++
+		}
+	}
+
+	return union
+}
+
+// State is what is known of the registers before an instruction.
+type State struct {
+	// Reached is false if no path from the entry point reaches the instruction.
+	Reached bool
+	A       Value
+	D       Value
+}
+
+// Access is the RAM an instruction may access through M.
+type Access struct {
+	// Reads and Writes are the addresses the instruction may read and write, sorted.
+	Reads  []uint16
+	Writes []uint16
+	// Unknown is true if the instruction accesses M through an A that may hold any value.
+	Unknown bool
+}
+
+// Kinds of the findings of the analysis.
+const (
+	FindingUndefinedD            = "undefined-d"
+	FindingUndefinedA            = "undefined-a"
+	FindingIOAccess              = "io-access"
+	FindingUninitializedVariable = "uninitialized-variable"
+)
+
+// Finding is a likely mistake found by the analysis.
+type Finding struct {
+	Position
+	// Address is the ROM address of the instruction.
+	Address uint
+	Kind    string
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("line %d, column %d: %s (%s)", f.Line, f.Column, f.Message, f.Kind)
+}
+
+// Analysis is the register dataflow of a program.
+type Analysis struct {
+	CFG *ControlFlowGraph
+
+	// States and Accesses are indexed by ROM address.
+	States   []State
+	Accesses []Access
+
+	// Findings are ordered by address, including the findings in the library routines used by the program.
+	Findings []Finding
+}
+
+// flowState is the state of the registers and of the variables flowing through the program.
+type flowState struct {
+	a, d Value
+
+	// written are the addresses of the variables written on every path.
+	written map[uint16]bool
+}
+
+func (s *flowState) clone() *flowState {
+	written := make(map[uint16]bool, len(s.written))
+	for address := range s.written {
+		written[address] = true
+	}
+
+	return &flowState{a: s.a, d: s.d, written: written}
+}
+
+// join merges the state into s, and returns true if s changed.
+func (s *flowState) join(t *flowState) bool {
+	a, d := s.a.join(t.a), s.d.join(t.d)
+	changed := !a.equal(s.a) || !d.equal(s.d)
+	s.a, s.d = a, d
+
+	for address := range s.written {
+		if !t.written[address] {
+			delete(s.written, address)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Analyze assembles the program without writing it, and computes what may be held by A and D
+// before each of its instructions, and what RAM each instruction may access through M.
+// The analysis runs over the control flow graph of BuildCFG, until no state changes.
+// M may hold any value, so the values loaded from it are unknown.
+func (c *Config) Analyze(program *Program) (*Analysis, error) {
+	g, err := c.BuildCFG(program)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &dataflow{
+		config: c,
+		analysis: &Analysis{
+			CFG:      g,
+			States:   make([]State, len(g.Instructions)),
+			Accesses: make([]Access, len(g.Instructions)),
+		},
+	}
+	err = d.run()
+	if err != nil {
+		return nil, err
+	}
+
+	return d.analysis, nil
+}
+
+// dataflow is the state of an analysis.
+type dataflow struct {
+	config   *Config
+	analysis *Analysis
+
+	// in are the states entering each block, nil until the block is reached.
+	in []*flowState
+}
+
+func (d *dataflow) run() error {
+	g := d.analysis.CFG
+	if len(g.Blocks) == 0 {
+		return nil
+	}
+
+	d.in = make([]*flowState, len(g.Blocks))
+	d.in[0] = &flowState{a: Value{Undefined: true}, d: Value{Undefined: true}, written: make(map[uint16]bool)}
+
+	queue := []int{0}
+	queued := map[int]bool{0: true}
+	for len(queue) > 0 {
+		b := queue[0]
+		queue = queue[1:]
+		delete(queued, b)
+
+		out, err := d.flow(b, false)
+		if err != nil {
+			return err
+		}
+
+		for _, edge := range g.Blocks[b].Successors {
+			if d.in[edge.To] == nil {
+				d.in[edge.To] = out.clone()
+			} else if !d.in[edge.To].join(out) {
+				continue
+			}
+			if !queued[edge.To] {
+				queued[edge.To] = true
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+
+	// The states are final: record them with the accesses and the findings.
+	for b := range g.Blocks {
+		if d.in[b] != nil {
+			if _, err := d.flow(b, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// flow returns the state leaving the block. If record is true, it records the states,
+// the accesses and the findings of its instructions.
+func (d *dataflow) flow(b int, record bool) (*flowState, error) {
+	block := d.analysis.CFG.Blocks[b]
+	s := d.in[b].clone()
+
+	for address := block.Start; address < block.End; address++ {
+		if record {
+			d.analysis.States[address] = State{Reached: true, A: s.a, D: s.d}
+		}
+
+		switch command := d.analysis.CFG.Instructions[address].(type) {
+		case *AInstruction:
+			s.a = Value{Values: []uint16{uint16(command.Value)}}
+		case *CInstruction:
+			err := d.execute(s, command, address, record)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// operands returns the registers read by the C-instruction, and whether y is M rather than A.
+func operands(word uint16, command *CInstruction) (readsD, readsY, readsM bool) {
+	if word>>PrefixShift != standardPrefix {
+		readsM = strings.Contains(command.Comp, "M")
+		return strings.Contains(command.Comp, "D"), readsM || strings.Contains(command.Comp, "A"), readsM
+	}
+
+	comp := word & CompMask >> CompShift
+	readsD = comp&0b100000 == 0
+	readsY = comp&0b001000 == 0
+
+	return readsD, readsY, readsY && word&ABit != 0
+}
+
+// execute runs the C-instruction on the state.
+func (d *dataflow) execute(s *flowState, command *CInstruction, address uint, record bool) error {
+	word, err := EncodeWith(d.config.code, command)
+	if err != nil {
+		return newSourceError(command, "%s: %w", command, err)
+	}
+
+	readsD, readsY, readsM := operands(word, command)
+	writesM := word&DestM != 0
+	if record {
+		d.check(s, command, address, word, readsD, readsY)
+		d.access(s, command, address, readsM, writesM)
+	}
+
+	out := Value{Any: true}
+	if word>>PrefixShift == standardPrefix {
+		out = evaluate(word, s, readsD, readsY, readsM)
+	}
+
+	if writesM {
+		if addresses, ok := s.a.known(); ok && len(addresses) == 1 && !s.a.Undefined {
+			s.written[addresses[0]] = true
+		}
+	}
+	if word&DestA != 0 {
+		s.a = out
+	}
+	if word&DestD != 0 {
+		s.d = out
+	}
+
+	return nil
+}
+
+// evaluate returns the values the ALU may output for the C-instruction.
+func evaluate(word uint16, s *flowState, readsD, readsY, readsM bool) Value {
+	xs := []uint16{0}
+	if readsD {
+		values, ok := s.d.known()
+		if !ok {
+			return Value{Any: true}
+		}
+		xs = values
+	}
+
+	ys := []uint16{0}
+	if readsM {
+		return Value{Any: true}
+	}
+	if readsY {
+		values, ok := s.a.known()
+		if !ok {
+			return Value{Any: true}
+		}
+		ys = values
+	}
+
+	var out Value
+	for _, x := range xs {
+		for _, y := range ys {
+			out = out.join(Value{Values: []uint16{alu(word, x, y)}})
+		}
+	}
+
+	return out
+}
+
+// alu returns the output of the ALU for the comp bits of the instruction.
+func alu(word uint16, x uint16, y uint16) uint16 {
+	comp := word & CompMask >> CompShift
+	if comp&0b100000 != 0 {
+		x = 0
+	}
+	if comp&0b010000 != 0 {
+		x = ^x
+	}
+	if comp&0b001000 != 0 {
+		y = 0
+	}
+	if comp&0b000100 != 0 {
+		y = ^y
+	}
+	out := x & y
+	if comp&0b000010 != 0 {
+		out = x + y
+	}
+	if comp&0b000001 != 0 {
+		out = ^out
+	}
+
+	return out
+}
+
+// check records the findings on the registers used by the C-instruction.
+// A is used if it is read, directly or as the address of M, or if it is the address written or jumped to.
+func (d *dataflow) check(s *flowState, command *CInstruction, address uint, word uint16, readsD, readsY bool) {
+	if readsD && s.d.Undefined {
+		d.report(command, address, FindingUndefinedD, "%s may read D before it is assigned", command)
+	}
+
+	usesA := readsY || word&DestM != 0 || word&JumpMask != 0
+	if usesA && s.a.Undefined {
+		d.report(command, address, FindingUndefinedA, "%s may use A before it is assigned", command)
+	}
+}
+
+// access records the RAM accessed by the C-instruction through M, and the findings on it.
+func (d *dataflow) access(s *flowState, command *CInstruction, address uint, readsM, writesM bool) {
+	if !readsM && !writesM {
+		return
+	}
+
+	addresses, ok := s.a.known()
+	if !ok {
+		d.analysis.Accesses[address] = Access{Unknown: true}
+		return
+	}
+
+	access := Access{}
+	if readsM {
+		access.Reads = addresses
+	}
+	if writesM {
+		access.Writes = addresses
+	}
+	d.analysis.Accesses[address] = access
+
+	m := d.config.memoryMap
+	for _, a := range addresses {
+		if uint(a) >= m.ScreenAddress && uint(a) <= m.KBDAddress {
+			d.report(command, address, FindingIOAccess, "%s may access the memory-mapped I/O at %d", command, a)
+			break
+		}
+	}
+
+	if !readsM {
+		return
+	}
+	for _, a := range addresses {
+		if name, ok := d.analysis.CFG.variables[a]; ok && !s.written[a] {
+			d.report(command, address, FindingUninitializedVariable,
+				"%s may read variable %s before it is written", command, name)
+			break
+		}
+	}
+}
+
+func (d *dataflow) report(node Node, address uint, kind string, format string, a ...any) {
+	d.analysis.Findings = append(d.analysis.Findings, Finding{
+		Position: node.Pos(),
+		Address:  address,
+		Kind:     kind,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
@@ -0,0 +1,151 @@
+package hack
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// analyzeSource analyzes the source with the standard configuration.
+func analyzeSource(t *testing.T, source string) *Analysis {
+	t.Helper()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := config.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := config.Analyze(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return analysis
+}
+
+func TestConfig_Analyze_Findings(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		want     []string
+	}{
+		{"clean", "@i\nM=0\n@i\nD=M\n@R0\nM=D\n", nil},
+		{"undefined d", "@R0\nM=D\n", []string{"undefined-d:2"}},
+		{"undefined a", "D=0\nM=D\n", []string{"undefined-a:2"}},
+		{"undefined jump target", "D=0\nD;JEQ\n", []string{"undefined-a:2"}},
+		{"defined on every path", "@R0\nD=M\n@SKIP\nD;JEQ\n@R1\nM=0\n(SKIP)\n@R2\nM=0\n", nil},
+		{"screen", "@SCREEN\nM=-1\n", []string{"io-access:2"}},
+		{"keyboard", "@KBD\nD=M\n", []string{"io-access:2"}},
+		{"screen offset", "@SCREEN\nD=A\n@32\nA=D+A\nM=-1\n", []string{"io-access:5"}},
+		{"uninitialized variable", "@x\nD=M\n", []string{"uninitialized-variable:2"}},
+		{"uninitialized on a path", "@R0\nD=M\n@SKIP\nD;JEQ\n@x\nM=0\n(SKIP)\n@x\nD=M\n", []string{"uninitialized-variable:9"}},
+		{"initialized in a loop", "@i\nM=0\n(LOOP)\n@i\nMD=M+1\n@LOOP\n0;JMP\n", nil},
+		{"unreached", "@END\n0;JMP\n@R0\nM=D\n(END)\n@END\n0;JMP\n", nil},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			var got []string
+			for _, f := range analyzeSource(t, d.source).Findings {
+				got = append(got, fmt.Sprintf("%s:%d", f.Kind, f.Line))
+			}
+			if diff := cmp.Diff(got, d.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestConfig_Analyze_Values(t *testing.T) {
+	t.Parallel()
+
+	analysis := analyzeSource(t, "@5\nD=A\n@3\nD=D+A\n@R0\nM=D\n@R0\nD=M\n")
+
+	want := []State{
+		{Reached: true, A: Value{Undefined: true}, D: Value{Undefined: true}},
+		{Reached: true, A: Value{Values: []uint16{5}}, D: Value{Undefined: true}},
+		{Reached: true, A: Value{Values: []uint16{5}}, D: Value{Values: []uint16{5}}},
+		{Reached: true, A: Value{Values: []uint16{3}}, D: Value{Values: []uint16{5}}},
+		{Reached: true, A: Value{Values: []uint16{3}}, D: Value{Values: []uint16{8}}},
+		{Reached: true, A: Value{Values: []uint16{0}}, D: Value{Values: []uint16{8}}},
+		{Reached: true, A: Value{Values: []uint16{0}}, D: Value{Values: []uint16{8}}},
+		{Reached: true, A: Value{Values: []uint16{0}}, D: Value{Values: []uint16{8}}},
+	}
+	if diff := cmp.Diff(analysis.States, want); diff != "" {
+		t.Error(diff)
+	}
+
+	wantAccesses := []Access{{}, {}, {}, {}, {}, {Writes: []uint16{0}}, {}, {Reads: []uint16{0}}}
+	if diff := cmp.Diff(analysis.Accesses, wantAccesses); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfig_Analyze_Join(t *testing.T) {
+	t.Parallel()
+
+	// D is 1 or 2 at END, and grows past the values tracked in LOOP.
+	source := "@R0\nD=M\n@TWO\nD;JEQ\nD=1\n@END\n0;JMP\n(TWO)\nD=1\nD=D+1\n(END)\n@R1\nM=D\n" +
+		"(LOOP)\nD=D+1\n@LOOP\n0;JMP\n"
+	analysis := analyzeSource(t, source)
+
+	if got, want := analysis.States[9].D, (Value{Values: []uint16{1, 2}}); !got.equal(want) {
+		t.Errorf("D at END is %s, want %s", got, want)
+	}
+	if got := analysis.States[11].D; !got.Any {
+		t.Errorf("D in LOOP is %s, want any", got)
+	}
+}
+
+func TestConfig_Analyze_Unreached(t *testing.T) {
+	t.Parallel()
+
+	analysis := analyzeSource(t, "@END\n0;JMP\nD=1\n(END)\n@END\n0;JMP\n")
+	if analysis.States[2].Reached {
+		t.Error("D=1 reached after an unconditional jump")
+	}
+	if !analysis.States[3].Reached {
+		t.Error("END not reached")
+	}
+}
+
+func TestConfig_Analyze_Library(t *testing.T) {
+	t.Parallel()
+
+	analysis := analyzeSource(t, ".use mult\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n@R0\nM=D\n(END)\n@END\n0;JMP\n")
+	for _, f := range analysis.Findings {
+		if analysis.CFG.InProgram(f.Address) {
+			t.Errorf("unexpected finding %s", f)
+		}
+	}
+}
+
+func TestValue_String(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		value Value
+		want  string
+	}{
+		{Value{Undefined: true}, "undefined"},
+		{Value{Any: true}, "any"},
+		{Value{Values: []uint16{1, 2}}, "{1, 2}"},
+		{Value{Values: []uint16{1}, Undefined: true}, "{1} or undefined"},
+	}
+
+	for _, d := range data {
+		if got := d.value.String(); got != d.want {
+			t.Errorf("got %q, want %q", got, d.want)
+		}
+	}
+}
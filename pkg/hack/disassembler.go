@@ -0,0 +1,86 @@
+package hack
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const instructionBits = 16
+
+// Disassembler is a struct that translates Hack machine code back into Hack assembly code.
+// Labels and variables can not be recovered, so A-instructions are written with numeric addresses.
+type Disassembler struct {
+	r       io.Reader
+	w       io.Writer
+	decoder InstructionDecoder
+}
+
+// NewDisassembler creates a new instance of the Disassembler.
+// The reader is used to read the machine code, while the writer is used to write the assembly code.
+func NewDisassembler(r io.Reader, w io.Writer) *Disassembler {
+	return &Disassembler{
+		r:       r,
+		w:       w,
+		decoder: NewCode(),
+	}
+}
+
+// SetInstructionSet sets the instruction set used to decode C-instructions.
+func (d *Disassembler) SetInstructionSet(decoder InstructionDecoder) {
+	d.decoder = decoder
+}
+
+// ErrInvalidInstruction is returned when a line of machine code is not a 16-bit instruction.
+var ErrInvalidInstruction = errors.New("invalid instruction")
+
+func (d *Disassembler) disassembleInstruction(bits string) (string, error) {
+	if len(bits) != instructionBits {
+		return "", fmt.Errorf("%s: %w", bits, ErrInvalidInstruction)
+	}
+
+	word, err := strconv.ParseUint(bits, 2, instructionBits)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", bits, ErrInvalidInstruction)
+	}
+
+	inst, err := DecodeWith(d.decoder, uint16(word))
+	if err != nil {
+		return "", err
+	}
+
+	return inst.String(), nil
+}
+
+// Disassemble reads the machine code, one instruction per line, and writes
+// one assembly command per instruction.
+// Blank lines are skipped.
+func (d *Disassembler) Disassemble() error {
+	scanner := bufio.NewScanner(newSourceReader(d.r))
+	scanner.Split(scanLines)
+
+	var line uint
+	for scanner.Scan() {
+		line++
+
+		bits := strings.TrimSpace(scanner.Text())
+		if bits == "" {
+			continue
+		}
+
+		command, err := d.disassembleInstruction(bits)
+		if err != nil {
+			return &SourceError{Line: line, Column: 1, Err: err}
+		}
+
+		_, err = d.w.Write([]byte(command + "\n"))
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
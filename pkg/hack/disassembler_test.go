@@ -0,0 +1,89 @@
+package hack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestDisassembler_Disassemble(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		binary   string
+		asm      string
+		code     Code
+	}{
+		{
+			testCase: "max",
+			binary:   maxCommandsBinary,
+			asm: `@0
+D=M
+@1
+D=D-M
+@12
+D;JGT
+@1
+D=M
+@2
+M=D
+@16
+0;JMP
+@0
+D=M
+@2
+M=D
+@16
+0;JMP
+`,
+			code: NewCode(),
+		},
+		{
+			testCase: "extended",
+			binary:   "1010110000010000\n\n1011000000101111\n",
+			asm:      "D=D<<\nAM=M>>;JMP\n",
+			code:     NewExtendedALUCode(),
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &bytes.Buffer{}
+			disassembler := NewDisassembler(strings.NewReader(d.binary), writer)
+			disassembler.SetInstructionSet(d.code)
+
+			err := disassembler.Disassemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(writer.String(), d.asm); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDisassembler_Disassemble_Invalid(t *testing.T) {
+	t.Parallel()
+
+	disassembler := NewDisassembler(strings.NewReader("0000000000000001\n1010110000010000\n"), &bytes.Buffer{})
+
+	err := disassembler.Disassemble()
+
+	var sourceErr *SourceError
+	if !errors.As(err, &sourceErr) || !errors.Is(err, ErrInvalidNemonic) {
+		t.Fatalf("expected invalid nemonic error, got %v", err)
+	}
+
+	if diff := cmp.Diff(sourceErr.Line, uint(2)); diff != "" {
+		t.Error(diff)
+	}
+}
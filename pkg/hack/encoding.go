@@ -0,0 +1,130 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Bit fields of Hack instructions.
+//
+//	A-instruction: 0vvv vvvv vvvv vvvv
+//	C-instruction: 111a cccc ccdd djjj
+const (
+	// CInstructionBit is set in C-instructions and clear in A-instructions.
+	CInstructionBit uint16 = 1 << 15
+	// AddressMask masks the value loaded by an A-instruction.
+	AddressMask uint16 = 1<<15 - 1
+
+	// PrefixMask masks the high bits of a C-instruction, 111 in the standard instruction set.
+	PrefixMask  uint16 = 0b111 << PrefixShift
+	PrefixShift        = 13
+	// CompMask masks the a-bit and the 6 comp bits of a C-instruction.
+	CompMask  uint16 = 0b1111111 << CompShift
+	CompShift        = 6
+	// ABit selects M instead of A as the second operand of the ALU.
+	ABit uint16 = 1 << 12
+	// DestMask masks the dest bits of a C-instruction.
+	DestMask  uint16 = 0b111 << DestShift
+	DestShift        = 3
+	// JumpMask masks the jump bits of a C-instruction.
+	JumpMask uint16 = 0b111
+
+	// Dest bits.
+	DestA uint16 = 0b100 << DestShift
+	DestD uint16 = 0b010 << DestShift
+	DestM uint16 = 0b001 << DestShift
+
+	// Jump bits.
+	JumpLT uint16 = 0b100
+	JumpEQ uint16 = 0b010
+	JumpGT uint16 = 0b001
+)
+
+// Instruction is an *AInstruction or a *CInstruction.
+type Instruction interface {
+	Node
+	instruction()
+}
+
+func (a *AInstruction) instruction() {}
+
+func (c *CInstruction) instruction() {}
+
+// standardCode is shared by Encode and Decode, and must not be extended.
+var standardCode = NewCode()
+
+// ErrUnresolvedSymbol is returned when encoding an A-instruction whose symbol has not been resolved.
+var ErrUnresolvedSymbol = errors.New("unresolved symbol")
+
+// Encode returns the machine code of the instruction in the standard instruction set.
+// A-instructions must load a constant Value, since symbols are resolved by the Assembler.
+func Encode(inst Instruction) (uint16, error) {
+	return EncodeWith(standardCode, inst)
+}
+
+// EncodeWith returns the machine code of the instruction in the given instruction set.
+func EncodeWith(is InstructionSet, inst Instruction) (uint16, error) {
+	switch command := inst.(type) {
+	case *AInstruction:
+		if command.Symbol != "" {
+			return 0, fmt.Errorf("%s: %w", command.Symbol, ErrUnresolvedSymbol)
+		}
+		if command.Value > uint(AddressMask) {
+			return 0, fmt.Errorf("%d does not fit into 15 bits: %w", command.Value, ErrAddressOutOfRange)
+		}
+		return uint16(command.Value), nil
+	case *CInstruction:
+		return encodeC(is, command)
+	}
+
+	return 0, fmt.Errorf("%v: %w", inst, ErrInvalidCommand)
+}
+
+// encodeC returns the machine code of the C-instruction,
+// whose fields are put in place with the shifts of the bit fields.
+func encodeC(is InstructionSet, command *CInstruction) (uint16, error) {
+	dest, err := is.Dest(command.Dest)
+	if err != nil {
+		return 0, err
+	}
+	comp, err := is.Comp(command.Comp)
+	if err != nil {
+		return 0, err
+	}
+	jump, err := is.Jump(command.Jump)
+	if err != nil {
+		return 0, err
+	}
+	prefix, err := is.Prefix(command.Comp)
+	if err != nil {
+		return 0, err
+	}
+
+	word := prefix<<PrefixShift | comp<<CompShift | dest<<DestShift | jump
+	if word&PrefixMask>>PrefixShift != prefix || word&CompMask>>CompShift != comp ||
+		word&DestMask>>DestShift != dest || word&JumpMask != jump {
+		return 0, fmt.Errorf("%s: fields do not fit into their bits: %w", command, ErrInvalidCommand)
+	}
+
+	return word, nil
+}
+
+// Decode returns the instruction of the machine code in the standard instruction set.
+// The returned instruction has no position.
+func Decode(word uint16) (Instruction, error) {
+	return DecodeWith(standardCode, word)
+}
+
+// DecodeWith returns the instruction of the machine code in the given instruction set.
+func DecodeWith(decoder InstructionDecoder, word uint16) (Instruction, error) {
+	if word&CInstructionBit == 0 {
+		return &AInstruction{Value: uint(word & AddressMask)}, nil
+	}
+
+	dest, comp, jump, err := decoder.Mnemonics(word)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CInstruction{Dest: dest, Comp: comp, Jump: jump}, nil
+}
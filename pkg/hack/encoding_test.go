@@ -0,0 +1,175 @@
+package hack
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestEncode(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		inst     Instruction
+		word     uint16
+		err      error
+	}{
+		{
+			testCase: "A-instruction",
+			inst:     &AInstruction{Value: 16384},
+			word:     0b0100000000000000,
+		},
+		{
+			testCase: "C-instruction",
+			inst:     &CInstruction{Dest: "AM", Comp: "M+1", Jump: "JGT"},
+			word:     0b1111110111101001,
+		},
+		{
+			testCase: "unresolved symbol",
+			inst:     &AInstruction{Symbol: "LOOP"},
+			err:      ErrUnresolvedSymbol,
+		},
+		{
+			testCase: "out of range",
+			inst:     &AInstruction{Value: 32768},
+			err:      ErrAddressOutOfRange,
+		},
+		{
+			testCase: "invalid comp",
+			inst:     &CInstruction{Dest: "D", Comp: "D<<"},
+			err:      ErrInvalidNemonic,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			word, err := Encode(d.inst)
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if diff := cmp.Diff(word, d.word); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestEncode_BitFields(t *testing.T) {
+	t.Parallel()
+
+	word, err := Encode(&CInstruction{Dest: "AM", Comp: "M+1", Jump: "JGT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []bool{
+		word&CInstructionBit != 0,
+		word&ABit != 0,
+		word&DestA != 0,
+		word&DestD != 0,
+		word&DestM != 0,
+		word&JumpLT != 0,
+		word&JumpEQ != 0,
+		word&JumpGT != 0,
+	}
+	if diff := cmp.Diff(got, []bool{true, true, true, false, true, false, false, true}); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff((word&CompMask)>>CompShift, uint16(0b1110111)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+// fieldInstructionSet encodes every mnemonic with the same fields.
+type fieldInstructionSet struct {
+	prefix, comp, dest, jump uint16
+}
+
+func (f fieldInstructionSet) Dest(string) (uint16, error)   { return f.dest, nil }
+func (f fieldInstructionSet) Comp(string) (uint16, error)   { return f.comp, nil }
+func (f fieldInstructionSet) Jump(string) (uint16, error)   { return f.jump, nil }
+func (f fieldInstructionSet) Prefix(string) (uint16, error) { return f.prefix, nil }
+
+func TestEncodeWith(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		is       fieldInstructionSet
+		word     uint16
+		err      error
+	}{
+		{
+			testCase: "fields",
+			is:       fieldInstructionSet{prefix: 0b100, comp: 0b0011000, dest: 0b010, jump: 0b001},
+			word:     0b1000011000010001,
+		},
+		{
+			testCase: "comp too wide",
+			is:       fieldInstructionSet{prefix: 0b111, comp: 0b10000000},
+			err:      ErrInvalidCommand,
+		},
+		{
+			testCase: "jump too wide",
+			is:       fieldInstructionSet{prefix: 0b111, jump: 0b1000},
+			err:      ErrInvalidCommand,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			word, err := EncodeWith(d.is, &CInstruction{Dest: "D", Comp: "D*2", Jump: "JGT"})
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatal(diff)
+			}
+
+			if diff := cmp.Diff(word, d.word); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	for dest := range standardDest {
+		for comp := range standardComp {
+			for jump := range standardJump {
+				inst := &CInstruction{Dest: dest, Comp: comp, Jump: jump}
+
+				word, err := Encode(inst)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				decoded, err := Decode(word)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if diff := cmp.Diff(decoded, Instruction(inst)); diff != "" {
+					t.Error(diff)
+				}
+			}
+		}
+	}
+
+	decoded, err := Decode(0b0000000000010001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(decoded, Instruction(&AInstruction{Value: 17})); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,194 @@
+package hack
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// statement is a single command on a source line and the column it starts at.
+// The parts of the command are split once by lexStatement, so that the parser
+// does not have to match the command again for each of its accessors.
+type statement struct {
+	text   string
+	column uint
+
+	kind CommandType
+
+	// symbol is the symbol of an A or L command, or the name of a directive, empty if it has none.
+	symbol string
+
+	// argument is the trimmed argument of a directive.
+	argument string
+
+	// dest, comp and jump are the trimmed parts of a C command.
+	dest string
+	comp string
+	jump string
+}
+
+// isSymbolStart returns true if c can start a symbol of an L command.
+// A leading '-' is accepted and left to the symbol validation.
+func isSymbolStart(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' ||
+		c == '_' || c == '.' || c == '$' || c == ':' || c == '-'
+}
+
+// isSymbolPart returns true if c can be part of the symbol of an A or L command.
+func isSymbolPart(c byte) bool {
+	return isSymbolStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// isNumber returns true if s is a non-empty run of decimal digits.
+func isNumber(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// lexSymbol returns the leading run of symbol characters of s.
+func lexSymbol(s string) string {
+	i := 0
+	for i < len(s) && isSymbolPart(s[i]) {
+		i++
+	}
+
+	return s[:i]
+}
+
+// lexStatement classifies the statement and splits it into its parts.
+// The text of the statement must be trimmed and free of separators.
+func lexStatement(text string, column uint) statement {
+	s := statement{text: text, column: column}
+
+	switch {
+	case strings.HasPrefix(text, "//"):
+		s.kind = commentCommand
+	case strings.HasPrefix(text, "@"):
+		s.kind = ACommand
+		// Text following the symbol is kept in it, so that it is rejected as an invalid symbol.
+		s.symbol = lexSymbol(text[1:])
+		if len(s.symbol) < len(text)-1 {
+			s.symbol = text[1:]
+		}
+	case strings.HasPrefix(text, DirectivePrefix):
+		s.kind = DirectiveCommand
+		name := text[len(DirectivePrefix):]
+		i := 0
+		for i < len(name) && !isBlank(name[i]) {
+			i++
+		}
+		s.symbol = name[:i]
+		s.argument = strings.TrimSpace(name[i:])
+	case strings.HasPrefix(text, "("):
+		s.kind = LCommand
+		if len(text) > 1 && isSymbolStart(text[1]) {
+			symbol := lexSymbol(text[1:])
+			if strings.HasPrefix(text[1+len(symbol):], ")") {
+				s.symbol = symbol
+			}
+		}
+	default:
+		s.kind = CCommand
+		rest := text
+		if i := strings.IndexAny(rest, "=;"); i >= 0 && rest[i] == '=' {
+			s.dest = strings.TrimSpace(rest[:i])
+			rest = rest[i+1:]
+		}
+		if i := strings.IndexByte(rest, ';'); i >= 0 {
+			s.jump = strings.TrimSpace(rest[i+1:])
+			rest = rest[:i]
+		}
+		s.comp = strings.TrimSpace(rest)
+	}
+
+	return s
+}
+
+// isPlain returns true if the line is ASCII without tabs, so that its columns are its byte offsets.
+func isPlain(line string) bool {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\t' || line[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isBlank returns true if c is a space or a tab.
+func isBlank(c byte) bool {
+	return c == ' ' || c == '\t'
+}
+
+// appendStatements splits a source line into its statements and appends them to dst.
+// A label definition may be directly followed by other statements on the same line,
+// and statements may be separated explicitly with StatementSeparator.
+// If keepComments is true, the comment of the line is appended as its last statement.
+// Columns are 1-based and count characters, with tabs expanded to multiples of tabWidth.
+func appendStatements(dst []statement, line string, keepComments bool, tabWidth int) []statement {
+	plain := isPlain(line)
+	column := func(offset int) uint {
+		if plain {
+			return uint(offset + 1)
+		}
+		return columnOf(line, offset, tabWidth)
+	}
+
+	code := line
+	comment := -1
+	if i := strings.Index(line, "//"); i >= 0 {
+		code = line[:i]
+		comment = i
+	}
+
+	start := 0
+	for start <= len(code) {
+		end := len(code)
+		if i := strings.Index(code[start:], StatementSeparator); i >= 0 {
+			end = start + i
+		}
+
+		for i := start; i < end; {
+			for i < end && isBlank(code[i]) {
+				i++
+			}
+			last := end
+			for last > i && isBlank(code[last-1]) {
+				last--
+			}
+			if i == last {
+				break
+			}
+
+			stop := last
+			if code[i] == '(' {
+				if j := strings.IndexByte(code[i:last], ')'); j >= 0 {
+					stop = i + j + 1
+				}
+			}
+
+			dst = append(dst, lexStatement(code[i:stop], column(i)))
+			i = stop
+		}
+
+		start = end + len(StatementSeparator)
+	}
+
+	if keepComments && comment >= 0 {
+		dst = append(dst, lexStatement(line[comment:], column(comment)))
+	}
+
+	return dst
+}
@@ -0,0 +1,59 @@
+package hack
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLexStatement(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		text     string
+		want     statement
+	}{
+		{"a-command", "@i", statement{text: "@i", kind: ACommand, symbol: "i"}},
+		{"a-command-trailing", "@R0 x", statement{text: "@R0 x", kind: ACommand, symbol: "R0 x"}},
+		{"a-command-empty", "@", statement{text: "@", kind: ACommand}},
+		{"l-command", "(LOOP)", statement{text: "(LOOP)", kind: LCommand, symbol: "LOOP"}},
+		{"l-command-unclosed", "(LOOP", statement{text: "(LOOP", kind: LCommand}},
+		{"l-command-digit", "(1X)", statement{text: "(1X)", kind: LCommand}},
+		{"c-command", "AM = M+1 ; JGT", statement{text: "AM = M+1 ; JGT", kind: CCommand, dest: "AM", comp: "M+1", jump: "JGT"}},
+		{"c-command-comp", "D", statement{text: "D", kind: CCommand, comp: "D"}},
+		{"c-command-jump-first", "0;J=", statement{text: "0;J=", kind: CCommand, comp: "0", jump: "J="}},
+		{"directive", ".export  LOOP", statement{text: ".export  LOOP", kind: DirectiveCommand, symbol: "export", argument: "LOOP"}},
+		{"directive-no-argument", ".file", statement{text: ".file", kind: DirectiveCommand, symbol: "file"}},
+		{"comment", "// c", statement{text: "// c", kind: commentCommand}},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			got := lexStatement(d.text, 0)
+			if diff := cmp.Diff(d.want, got, cmp.AllowUnexported(statement{})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestAppendStatements(t *testing.T) {
+	t.Parallel()
+
+	got := appendStatements(nil, "  (L) @1 ;; D=A // c", true, DefaultTabWidth)
+	want := []string{"(L)", "@1", "D=A", "// c"}
+	columns := []uint{3, 7, 13, 17}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d statements, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i].text != want[i] || got[i].column != columns[i] {
+			t.Errorf("statement %d: got %q at %d, want %q at %d", i, got[i].text, got[i].column, want[i], columns[i])
+		}
+	}
+}
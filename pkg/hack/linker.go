@@ -0,0 +1,181 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Linker links objects assembled separately into Hack machine code.
+// The objects are laid out in ROM in the given order. The imports of each object are
+// bound to the labels exported by the objects, and the imports exported by no object
+// are allocated as variables, in the order of their first use.
+// Variables are shared by all the objects, except the private variables of files, qualified as File.name.
+// The routines of the standard library used by the objects are linked once each, after the objects.
+// Linking the objects of several programs gives the same machine code as
+// assembling the concatenation of the programs.
+type Linker struct {
+	w      io.Writer
+	config *Config
+
+	warnings []error
+	size     int
+}
+
+// NewLinker creates a Linker writing the machine code to w,
+// for the standard Hack computer unless changed by the options.
+func NewLinker(w io.Writer, opts ...Option) (*Linker, error) {
+	config, err := NewConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return config.NewLinker(w), nil
+}
+
+// NewLinker creates a Linker writing the machine code to w.
+func (c *Config) NewLinker(w io.Writer) *Linker {
+	return &Linker{w: w, config: c}
+}
+
+// ErrDuplicateExport is returned when several objects export the same label.
+var ErrDuplicateExport = errors.New("label exported by several objects")
+
+// Warnings returns the warnings reported by the last call to Link.
+func (l *Linker) Warnings() []error {
+	return l.warnings
+}
+
+// Size returns the number of instructions written by the last call to Link.
+func (l *Linker) Size() int {
+	return l.size
+}
+
+// objectName returns the name of the object in errors.
+func objectName(object *Object, i int) string {
+	if object.Name != "" {
+		return object.Name
+	}
+	return fmt.Sprintf("object %d", i+1)
+}
+
+// Link links the objects and writes the machine code.
+// Nothing is written if the link fails.
+func (l *Linker) Link(objects ...*Object) error {
+	l.warnings = nil
+	l.size = 0
+
+	words, err := l.link(objects)
+	if err != nil {
+		return err
+	}
+
+	l.size = len(words)
+
+	return writeWords(l.w, l.config.format, words)
+}
+
+func (l *Linker) link(objects []*Object) ([]uint16, error) {
+	objects, err := l.includeLibrary(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	bases, exports, size, err := l.layout(objects)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]uint16, 0, size)
+	variables := make(map[string]uint)
+	nextAddress := l.config.variableBase
+
+	for i, object := range objects {
+		base := bases[i]
+		words = append(words, object.Words...)
+
+		for _, address := range object.Relocations {
+			words[base+address] += uint16(base)
+		}
+
+		for _, imp := range object.Imports {
+			variable := imp.Variable
+			if variable == "" {
+				variable = imp.Symbol
+			}
+
+			address, ok := exports[imp.Symbol]
+			if !ok {
+				address, ok = variables[variable]
+			}
+			if !ok {
+				address, err = l.allocateVariable(variable, nextAddress)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", objectName(object, i), err)
+				}
+				variables[variable] = address
+				nextAddress++
+			}
+
+			for _, at := range imp.Addresses {
+				words[base+at] = uint16(address)
+			}
+		}
+	}
+
+	return words, nil
+}
+
+// layout places the objects one after the other in ROM.
+// It returns the address of each object, the addresses of the exported labels and the size of the program.
+func (l *Linker) layout(objects []*Object) ([]uint, map[string]uint, uint, error) {
+	bases := make([]uint, len(objects))
+	exports := make(map[string]uint)
+	exporters := make(map[string]string)
+
+	var size uint
+	for i, object := range objects {
+		name := objectName(object, i)
+
+		err := object.Validate()
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("%s: %w", name, err)
+		}
+
+		bases[i] = size
+		size += uint(len(object.Words))
+		if size > l.config.memoryMap.ROMSize {
+			return nil, nil, 0, fmt.Errorf("%s: program exceeds %d words: %w", name, l.config.memoryMap.ROMSize, ErrROMOverflow)
+		}
+
+		for symbol, address := range object.Exports {
+			if exporter, ok := exporters[symbol]; ok {
+				return nil, nil, 0, fmt.Errorf("%s: %s also exported by %s: %w", name, symbol, exporter, ErrDuplicateExport)
+			}
+			if bases[i]+address >= l.config.memoryMap.ROMSize {
+				return nil, nil, 0, fmt.Errorf("%s: label %s resolves to %d: %w", name, symbol, bases[i]+address, ErrROMOverflow)
+			}
+			exports[symbol] = bases[i] + address
+			exporters[symbol] = name
+		}
+	}
+
+	return bases, exports, size, nil
+}
+
+// allocateVariable checks that the variable can be allocated at the address.
+func (l *Linker) allocateVariable(symbol string, address uint) (uint, error) {
+	if address >= l.config.memoryMap.RAMSize {
+		return 0, fmt.Errorf("no RAM left for variable %s: %w", symbol, ErrRAMOverflow)
+	}
+
+	if warning := l.config.memoryMap.checkVariable(address); warning != nil {
+		warning = fmt.Errorf("%s at %d: %w", symbol, address, warning)
+		if l.config.strict {
+			return 0, warning
+		}
+		l.warnings = append(l.warnings, warning)
+	}
+
+	return address, nil
+}
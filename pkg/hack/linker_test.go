@@ -0,0 +1,217 @@
+package hack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// assembleObject assembles the module and reads back its object.
+func assembleObject(t *testing.T, name string, asm string) *Object {
+	t.Helper()
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader(asm), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.AssembleObject()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := ReadObject(writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object.Name = name
+
+	return object
+}
+
+func TestLinker_Link(t *testing.T) {
+	t.Parallel()
+
+	modules := []string{
+		// The main module calls a routine of the second module through the variable ret.
+		"@i\nM=0\n@RET\nD=A\n@ret\nM=D\n@DOUBLE\n0;JMP\n(RET)\n@END\n(END)\n0;JMP\n",
+		"(DOUBLE)\n@i\nM=M+1\n@j\nM=D\n@SCREEN\nD=A\n@ret\nA=M\n0;JMP\n(LOOP)\n@LOOP\n0;JMP\n",
+		"@j\nD=M\n@k\nM=D\n@DOUBLE\n0;JMP\n",
+	}
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader(strings.Join(modules, "")), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make([]*Object, len(modules))
+	for i, module := range modules {
+		objects[i] = assembleObject(t, "module", module)
+	}
+
+	linked := &bytes.Buffer{}
+	linker, err := NewLinker(linked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = linker.Link(objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(linked.String(), writer.String()); diff != "" {
+		t.Error(diff)
+	}
+	if linker.Size() != assembler.Size() {
+		t.Errorf("expected %d words, got %d", assembler.Size(), linker.Size())
+	}
+}
+
+func TestLinker_Link_Library(t *testing.T) {
+	t.Parallel()
+
+	// Both modules use std.mult, which is linked once after them.
+	modules := []string{
+		".use mult\n@6\nD=A\n@R13\nM=D\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n@SQUARE\n0;JMP\n",
+		".use mult\n.use memset\n(SQUARE)\n@R13\nD=M\n@R14\nM=D\n@END\nD=A\n@std.mult\n0;JMP\n(END)\n@END\n0;JMP\n",
+	}
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader(strings.Join(modules, "")), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	objects := make([]*Object, len(modules))
+	for i, module := range modules {
+		objects[i] = assembleObject(t, "module", module)
+	}
+	if diff := cmp.Diff(objects[1].Uses, []string{"mult", "memset"}); diff != "" {
+		t.Error(diff)
+	}
+
+	linked := &bytes.Buffer{}
+	linker, err := NewLinker(linked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = linker.Link(objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(linked.String(), writer.String()); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAssembler_AssembleObject(t *testing.T) {
+	t.Parallel()
+
+	object := assembleObject(t, "main", "(START)\n@x\nM=0\n@START\n0;JMP\n@SP\n@x\n@END\n")
+
+	want := &Object{
+		Name:        "main",
+		Format:      ObjectFormat,
+		Version:     ObjectVersion,
+		Words:       []uint16{0, 0b1110101010001000, 0, 0b1110101010000111, 0, 0, 0},
+		Exports:     map[string]uint{"START": 0},
+		Relocations: []uint{2},
+		Imports: []Import{
+			{Symbol: "x", Addresses: []uint{0, 5}},
+			{Symbol: "END", Addresses: []uint{6}},
+		},
+	}
+
+	if diff := cmp.Diff(object, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLinker_Link_Errors(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		modules  []string
+		opts     []Option
+		err      error
+	}{
+		{
+			testCase: "duplicate export",
+			modules:  []string{"(L)\n@L\n", "(L)\n0;JMP\n"},
+			err:      ErrDuplicateExport,
+		},
+		{
+			testCase: "ROM overflow",
+			modules:  []string{"@1\n@2\n", "@3\n"},
+			opts:     []Option{WithMemoryMap(MemoryMap{ROMSize: 2, RAMSize: 20, ScreenAddress: 17, KBDAddress: 19})},
+			err:      ErrROMOverflow,
+		},
+		{
+			testCase: "RAM overflow",
+			modules:  []string{"@a\n@b\n", "@c\n@d\n@e\n"},
+			opts:     []Option{WithMemoryMap(MemoryMap{ROMSize: 10, RAMSize: 20, ScreenAddress: 17, KBDAddress: 19})},
+			err:      ErrRAMOverflow,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			objects := make([]*Object, len(d.modules))
+			for i, module := range d.modules {
+				objects[i] = assembleObject(t, "", module)
+			}
+
+			writer := &bytes.Buffer{}
+			linker, err := NewLinker(writer, d.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = linker.Link(objects...)
+			if !errors.Is(err, d.err) {
+				t.Errorf("expected %v, got %v", d.err, err)
+			}
+			if writer.Len() != 0 {
+				t.Errorf("expected no output, got %q", writer.String())
+			}
+		})
+	}
+}
+
+func TestReadObject_Invalid(t *testing.T) {
+	t.Parallel()
+
+	data := []string{
+		`{"format":"hack-object","version":2,"words":[]}`,
+		`{"format":"hack-object","version":1,"words":[0],"relocations":[1]}`,
+		`{"format":"hack-object","version":1,"words":[0],"imports":[{"symbol":"x","addresses":[3]}]}`,
+		`{"format":"hack-object","version":1,"words":[0],"exports":{"1x":0}}`,
+		`{"format":"hack-object","version":1,"words":[0],"unknown":1}`,
+		`{"format":"hack-object","version":1,"words":[0],"uses":["sqrt"]}`,
+	}
+
+	for _, d := range data {
+		_, err := ReadObject(strings.NewReader(d))
+		if !errors.Is(err, ErrInvalidObject) {
+			t.Errorf("%s: expected ErrInvalidObject, got %v", d, err)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Names of the rules of the Linter.
+const (
+	RuleUnusedLabel       = "unused-label"
+	RuleUnreachableCode   = "unreachable-code"
+	RuleComputedJump      = "computed-jump"
+	RuleStaleA            = "stale-a"
+	RuleSingleUseVariable = "single-use-variable"
+	RuleShadowedSymbol    = "shadowed-symbol"
+	RuleMissingHalt       = "missing-halt"
+
+	RuleUndefinedRegister     = "undefined-register"
+	RuleUninitializedVariable = "uninitialized-variable"
+)
+
+// LintRule is a check of the Linter.
+type LintRule struct {
+	Name        string
+	Description string
+}
+
+// LintRules returns the rules of the Linter, all enabled by default.
+func LintRules() []LintRule {
+	return []LintRule{
+		{RuleUnusedLabel, "labels never referred to"},
+		{RuleUnreachableCode, "instructions following an unconditional jump"},
+		{RuleComputedJump, "jumps whose preceding A-instruction is not a label or a constant"},
+		{RuleStaleA, "C-instructions writing A and M while reading M, which write M through the previous A"},
+		{RuleSingleUseVariable, "variables used exactly once, which are likely typos"},
+		{RuleShadowedSymbol, "labels shadowing predefined symbols such as R1 or SCREEN"},
+		{RuleMissingHalt, "programs that can run past their last instruction instead of ending in an infinite loop"},
+		{RuleUndefinedRegister, "instructions that may use A or D before they are assigned on some path"},
+		{RuleUninitializedVariable, "variables that may be read before they are written on some path"},
+	}
+}
+
+// ErrUnknownRule is returned when a lint rule is not known.
+var ErrUnknownRule = errors.New("unknown lint rule")
+
+// IgnoreComment starts a comment suppressing the diagnostics of the rules it names,
+// or of every rule if it names none, as in // hack:ignore unused-label.
+// The comment applies to the line it ends, or to the next statement if it is on a line of its own.
+const IgnoreComment = "hack:ignore"
+
+// Diagnostic is a likely mistake reported by the Linter.
+type Diagnostic struct {
+	Position
+	Rule    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d, column %d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// Linter checks parsed programs for likely mistakes.
+// It resolves symbols as the assemblers of its Config do.
+type Linter struct {
+	config   *Config
+	disabled map[string]bool
+}
+
+// NewLinter creates a Linter with every rule enabled.
+func (c *Config) NewLinter() *Linter {
+	return &Linter{config: c, disabled: make(map[string]bool)}
+}
+
+func isLintRule(name string) bool {
+	for _, rule := range LintRules() {
+		if rule.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Disable disables the rules.
+func (l *Linter) Disable(rules ...string) error {
+	for _, rule := range rules {
+		if !isLintRule(rule) {
+			return fmt.Errorf("%s: %w", rule, ErrUnknownRule)
+		}
+		l.disabled[rule] = true
+	}
+
+	return nil
+}
+
+// Enable enables the rules.
+func (l *Linter) Enable(rules ...string) error {
+	for _, rule := range rules {
+		if !isLintRule(rule) {
+			return fmt.Errorf("%s: %w", rule, ErrUnknownRule)
+		}
+		delete(l.disabled, rule)
+	}
+
+	return nil
+}
+
+// lintInstruction is an instruction of the program being linted.
+type lintInstruction struct {
+	node Node
+	file string
+
+	// labeled is true if a label is defined right before the instruction.
+	labeled bool
+}
+
+// lint is the state of the linting of a program.
+type lint struct {
+	linter *Linter
+	ns     *namespaces
+
+	// instructions are the instructions of the program.
+	instructions []lintInstruction
+
+	// labels are the labels of the program, and defined the labels of the program
+	// and of the library routines it uses, named as in the symbol table.
+	labels  map[string]*Label
+	defined map[string]bool
+
+	// references are the A-instructions referring to each symbol, named as in the symbol table.
+	references map[string][]*AInstruction
+
+	diagnostics []Diagnostic
+}
+
+// Lint returns the diagnostics of the program, ordered by position.
+// The routines of the standard library used by the program are resolved, but not checked.
+func (l *Linter) Lint(program *Program) []Diagnostic {
+	errs := errorList{}
+	full := l.config.NewAssembler(nil, io.Discard).includeLibrary(program, &errs)
+
+	s := &lint{
+		linter:     l,
+		ns:         newNamespaces(full, &errs),
+		labels:     make(map[string]*Label),
+		defined:    make(map[string]bool),
+		references: make(map[string][]*AInstruction),
+	}
+	s.collect(full, len(program.Nodes))
+
+	s.checkLabels()
+	s.checkInstructions()
+	s.checkVariables()
+	s.checkHalt()
+	s.checkDataflow(program)
+
+	diagnostics := suppress(program, s.diagnostics)
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		if a.Column != b.Column {
+			return a.Column < b.Column
+		}
+		return a.Rule < b.Rule
+	})
+
+	return diagnostics
+}
+
+// collect collects the labels and the instructions of the program,
+// and the references of its first n nodes, which are the nodes of the program being linted.
+func (s *lint) collect(program *Program, n int) {
+	file := ""
+	labeled := false
+	for i, node := range program.Nodes {
+		file = nextFile(node, file)
+
+		switch command := node.(type) {
+		case *Label:
+			name := s.ns.label(file, command.Name)
+			s.defined[name] = true
+			if i < n {
+				s.labels[name] = command
+			}
+			labeled = true
+		case *AInstruction, *CInstruction:
+			if a, ok := command.(*AInstruction); ok && a.Symbol != "" && i < n {
+				name := s.ns.reference(file, a.Symbol, s.linter.config.predefined)
+				s.references[name] = append(s.references[name], a)
+			}
+			if i < n {
+				s.instructions = append(s.instructions, lintInstruction{node: node, file: file, labeled: labeled})
+			}
+			labeled = false
+		}
+	}
+}
+
+// report adds a diagnostic of the rule, unless the rule is disabled.
+func (s *lint) report(node Node, rule string, format string, a ...any) {
+	if s.linter.disabled[rule] {
+		return
+	}
+
+	diagnostic := Diagnostic{Position: node.Pos(), Rule: rule, Message: fmt.Sprintf(format, a...)}
+	s.diagnostics = append(s.diagnostics, diagnostic)
+}
+
+// checkLabels checks the unused-label and shadowed-symbol rules.
+func (s *lint) checkLabels() {
+	for name, label := range s.labels {
+		if s.linter.config.predefined.Contains(label.Name) {
+			s.report(label, RuleShadowedSymbol, "label %s shadows a predefined symbol", label.Name)
+		}
+		if len(s.references[name]) == 0 && !s.ns.kept[name] && !s.ns.exported(name) {
+			s.report(label, RuleUnusedLabel, "label %s is never used", label.Name)
+		}
+	}
+}
+
+// checkInstructions checks the unreachable-code, computed-jump and stale-a rules.
+func (s *lint) checkInstructions() {
+	// The instructions following a jump are reachable only through a label,
+	// and only the first of them is reported.
+	unreachable, reported := false, false
+	for i, instruction := range s.instructions {
+		if instruction.labeled {
+			unreachable = false
+		}
+		if unreachable && !reported {
+			s.report(instruction.node, RuleUnreachableCode,
+				"%s can never run after an unconditional jump", instruction.node)
+			reported = true
+		}
+
+		command, ok := instruction.node.(*CInstruction)
+		if !ok {
+			continue
+		}
+
+		if command.Jump != "" && !s.isConstantTarget(i) {
+			s.report(command, RuleComputedJump, "%s jumps to an address that is not a label or a constant", command)
+		}
+		writesAM := strings.Contains(command.Dest, "A") && strings.Contains(command.Dest, "M")
+		if writesAM && strings.Contains(command.Comp, "M") {
+			s.report(command, RuleStaleA, "%s writes M at the address read, not at the new A", command)
+		}
+
+		if isUnconditionalJump(command) && !unreachable {
+			unreachable, reported = true, false
+		}
+	}
+}
+
+// isConstantTarget returns true if the jump at index i is preceded by an A-instruction
+// loading a label, a predefined symbol or a number.
+func (s *lint) isConstantTarget(i int) bool {
+	if i == 0 || s.instructions[i].labeled {
+		return false
+	}
+
+	previous := s.instructions[i-1]
+	command, ok := previous.node.(*AInstruction)
+	if !ok {
+		return false
+	}
+	if command.Symbol == "" {
+		return true
+	}
+
+	name := s.ns.reference(previous.file, command.Symbol, s.linter.config.predefined)
+	return s.defined[name] || s.linter.config.predefined.Contains(name)
+}
+
+// checkVariables checks the single-use-variable rule.
+func (s *lint) checkVariables() {
+	for name, references := range s.references {
+		if s.defined[name] || s.linter.config.predefined.Contains(name) || len(references) != 1 {
+			continue
+		}
+
+		s.report(references[0], RuleSingleUseVariable, "variable %s is used only once", references[0].Symbol)
+	}
+}
+
+// checkHalt checks the missing-halt rule.
+func (s *lint) checkHalt() {
+	if len(s.instructions) == 0 {
+		return
+	}
+
+	last := s.instructions[len(s.instructions)-1].node
+	if command, ok := last.(*CInstruction); ok && isUnconditionalJump(command) {
+		return
+	}
+
+	s.report(last, RuleMissingHalt,
+		"the program can run past %s; end it with an infinite loop such as (END) @END 0;JMP", last)
+}
+
+// checkDataflow checks the undefined-register and uninitialized-variable rules
+// with the findings of the dataflow analysis, unless the program can not be assembled.
+func (s *lint) checkDataflow(program *Program) {
+	analysis, err := s.linter.config.Analyze(program)
+	if err != nil {
+		return
+	}
+
+	for _, f := range analysis.Findings {
+		if !analysis.CFG.InProgram(f.Address) {
+			continue
+		}
+
+		node := analysis.CFG.Instructions[f.Address]
+		switch f.Kind {
+		case FindingUndefinedA, FindingUndefinedD:
+			s.report(node, RuleUndefinedRegister, "%s", f.Message)
+		case FindingUninitializedVariable:
+			s.report(node, RuleUninitializedVariable, "%s", f.Message)
+		}
+	}
+}
+
+// exported returns true if the label, named as in the symbol table, is exported by its file.
+func (n *namespaces) exported(name string) bool {
+	for file, labels := range n.labels {
+		if file != "" && labels[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suppress removes the diagnostics suppressed by the IgnoreComment comments of the program.
+func suppress(program *Program, diagnostics []Diagnostic) []Diagnostic {
+	// ignored maps lines to the rules ignored on them, or to nil if every rule is.
+	ignored := make(map[uint][]string)
+	var pending *Comment
+	lastLine := uint(0)
+
+	for _, node := range program.Nodes {
+		line := node.Pos().Line
+		comment, ok := node.(*Comment)
+		if !ok {
+			if pending != nil {
+				ignored[line] = ignoredRules(pending)
+				pending = nil
+			}
+			lastLine = line
+			continue
+		}
+		if comment.Text != IgnoreComment && !strings.HasPrefix(comment.Text, IgnoreComment+" ") {
+			continue
+		}
+
+		if line == lastLine {
+			ignored[line] = ignoredRules(comment)
+		} else {
+			pending = comment
+		}
+	}
+
+	kept := diagnostics[:0]
+	for _, d := range diagnostics {
+		rules, ok := ignored[d.Line]
+		if ok && (rules == nil || contains(rules, d.Rule)) {
+			continue
+		}
+		kept = append(kept, d)
+	}
+
+	return kept
+}
+
+// ignoredRules returns the rules named by an IgnoreComment comment, or nil if it names none.
+func ignoredRules(comment *Comment) []string {
+	rules := strings.FieldsFunc(strings.TrimPrefix(comment.Text, IgnoreComment), func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return rules
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
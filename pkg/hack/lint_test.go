@@ -0,0 +1,114 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// lintSource lints the source with the standard configuration, disabling the rules,
+// and returns the rule and the line of each diagnostic.
+func lintSource(t *testing.T, source string, disabled ...string) []string {
+	t.Helper()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := config.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	linter := config.NewLinter()
+	err = linter.Disable(disabled...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, d := range linter.Lint(program) {
+		got = append(got, fmt.Sprintf("%s:%d", d.Rule, d.Line))
+	}
+
+	return got
+}
+
+const halt = "(END)\n@END\n0;JMP\n"
+
+func TestLinter_Lint(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		want     []string
+	}{
+		{"clean", "@i\nM=0\n@i\nM=M+1\n" + halt, nil},
+		{"unused label", "(L)\n@1\n" + halt, []string{"unused-label:1"}},
+		{"exported label", ".file A\n.export L\n(L)\n" + halt, nil},
+		{"kept label", ".keep L\n(L)\n" + halt, nil},
+		{"unreachable code", "@END\n0;JMP\n@1\nD=A\n" + halt, []string{"unreachable-code:3"}},
+		{"computed jump", "@ret\nM=0\n@ret\nA=M\n0;JMP\n", []string{"computed-jump:5"}},
+		{"variable jump", "@ret\nM=0\n@ret\n0;JMP\n", []string{"computed-jump:4"}},
+		{"labeled jump", "@END\n(L)\n0;JMP\n@L\n" + halt, []string{"computed-jump:3", "unreachable-code:4"}},
+		{"constant jump", "D=0\n@R1\nD;JGT\n@3\n0;JMP\n", nil},
+		{"stale a", "@SP\nAM=M-1\n" + halt, []string{"stale-a:2"}},
+		{"single use variable", "@counter\nM=0\n" + halt, []string{"single-use-variable:1"}},
+		{"shadowed symbol", ".file A\n(R1)\n@R1\n" + halt, []string{"shadowed-symbol:2"}},
+		{"missing halt", "@1\nD=A\n", []string{"missing-halt:2"}},
+		{"routine after halt", halt + "(F)\n@1\nD=A\n@END\n0;JMP\n", []string{"unused-label:4"}},
+		{"undefined register", "@R0\nM=D\n" + halt, []string{"undefined-register:2"}},
+		{"uninitialized variable", "@x\nD=M\n@x\nM=D\n" + halt, []string{"uninitialized-variable:2"}},
+		{"library routine", ".use mult\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n" + halt, nil},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(lintSource(t, d.source), d.want); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLinter_Lint_Suppression(t *testing.T) {
+	t.Parallel()
+
+	source := "(A) // hack:ignore unused-label\n" +
+		"// hack:ignore\n" +
+		"(B)\n" +
+		"(C) // hack:ignore stale-a, missing-halt\n" +
+		"// hack:ignored\n" +
+		"(D)\n" +
+		halt
+
+	want := []string{"unused-label:4", "unused-label:6"}
+	if diff := cmp.Diff(lintSource(t, source), want); diff != "" {
+		t.Error(diff)
+	}
+
+	if diff := cmp.Diff(lintSource(t, source, RuleUnusedLabel), []string(nil)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLinter_Disable_UnknownRule(t *testing.T) {
+	t.Parallel()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = config.NewLinter().Disable("unused")
+	if !errors.Is(err, ErrUnknownRule) {
+		t.Errorf("expected ErrUnknownRule, got %v", err)
+	}
+}
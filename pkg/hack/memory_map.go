@@ -0,0 +1,85 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MemoryMap describes the address space of a Hack computer.
+// The standard nand2tetris computer has a 32K ROM, and a RAM whose
+// memory-mapped screen starts at 16384 and whose keyboard register is at 24576.
+type MemoryMap struct {
+	ROMSize       uint `json:"romSize" toml:"romSize"`
+	RAMSize       uint `json:"ramSize" toml:"ramSize"`
+	ScreenAddress uint `json:"screen"  toml:"screen"`
+	KBDAddress    uint `json:"kbd"     toml:"kbd"`
+}
+
+const (
+	screenAddress uint = 16384
+	kbdAddress    uint = 24576
+
+	romSize uint = 32768
+	ramSize      = kbdAddress + 1
+
+	// maxAddress is the largest value an A-instruction can load.
+	maxAddress uint = 1<<15 - 1
+)
+
+// StandardMemoryMap returns the memory map of the standard Hack computer.
+func StandardMemoryMap() MemoryMap {
+	return MemoryMap{
+		ROMSize:       romSize,
+		RAMSize:       ramSize,
+		ScreenAddress: screenAddress,
+		KBDAddress:    kbdAddress,
+	}
+}
+
+// ErrInvalidMemoryMap is returned when a memory map can not describe a Hack computer.
+var ErrInvalidMemoryMap = errors.New("invalid memory map")
+
+// Validate returns an error if the memory map is not addressable by A-instructions
+// or if its screen and keyboard regions are not inside the RAM.
+func (m MemoryMap) Validate() error {
+	if m.ROMSize == 0 || m.ROMSize > maxAddress+1 {
+		return fmt.Errorf("ROM size must be between 1 and %d: %w", maxAddress+1, ErrInvalidMemoryMap)
+	}
+	if m.RAMSize == 0 || m.RAMSize > maxAddress+1 {
+		return fmt.Errorf("RAM size must be between 1 and %d: %w", maxAddress+1, ErrInvalidMemoryMap)
+	}
+	if m.ScreenAddress >= m.KBDAddress {
+		return fmt.Errorf("screen must start below the keyboard: %w", ErrInvalidMemoryMap)
+	}
+	if m.KBDAddress >= m.RAMSize {
+		return fmt.Errorf("keyboard must be inside the RAM: %w", ErrInvalidMemoryMap)
+	}
+
+	return nil
+}
+
+var (
+	// ErrROMOverflow is returned when a program or a label does not fit into the ROM.
+	ErrROMOverflow = errors.New("ROM overflow")
+	// ErrRAMOverflow is returned when a variable can not be allocated inside the RAM.
+	ErrRAMOverflow = errors.New("RAM overflow")
+	// ErrAddressOutOfRange is returned when an A-instruction constant does not fit into 15 bits.
+	ErrAddressOutOfRange = errors.New("address out of range")
+
+	// ErrScreenVariable is reported as a warning when a variable is allocated in the screen region.
+	ErrScreenVariable = errors.New("variable allocated in the screen memory map")
+	// ErrKeyboardVariable is reported as a warning when a variable is allocated at the keyboard register.
+	ErrKeyboardVariable = errors.New("variable allocated at the keyboard memory map")
+)
+
+// checkVariable returns a warning if the variable address is inside an I/O region.
+func (m MemoryMap) checkVariable(address uint) error {
+	if address == m.KBDAddress {
+		return ErrKeyboardVariable
+	}
+	if address >= m.ScreenAddress && address < m.KBDAddress {
+		return ErrScreenVariable
+	}
+
+	return nil
+}
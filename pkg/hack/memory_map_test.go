@@ -0,0 +1,51 @@
+package hack
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMemoryMap_Validate(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase  string
+		memoryMap MemoryMap
+		err       error
+	}{
+		{
+			testCase:  "standard",
+			memoryMap: StandardMemoryMap(),
+			err:       nil,
+		},
+		{
+			testCase:  "ROM not addressable",
+			memoryMap: MemoryMap{ROMSize: 40000, RAMSize: 24577, ScreenAddress: 16384, KBDAddress: 24576},
+			err:       ErrInvalidMemoryMap,
+		},
+		{
+			testCase:  "keyboard outside RAM",
+			memoryMap: MemoryMap{ROMSize: 1024, RAMSize: 2048, ScreenAddress: 1024, KBDAddress: 2048},
+			err:       ErrInvalidMemoryMap,
+		},
+		{
+			testCase:  "screen above keyboard",
+			memoryMap: MemoryMap{ROMSize: 1024, RAMSize: 2048, ScreenAddress: 1500, KBDAddress: 1024},
+			err:       ErrInvalidMemoryMap,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			err := d.memoryMap.Validate()
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package hack
+
+import (
+	"errors"
+	"strings"
+)
+
+// DirectivePrefix starts a directive, followed by its name and its argument.
+const DirectivePrefix = "."
+
+// Names of the directives.
+const (
+	// FileDirective starts the namespace of a file, as in .file Main.
+	FileDirective = "file"
+	// ExportDirective makes a label of the file visible to the other files, as in .export LOOP.
+	ExportDirective = "export"
+	// KeepDirective keeps a routine removed by WithStrip otherwise, as in .keep ISR.
+	KeepDirective = "keep"
+)
+
+// directives are the names of the directives known to the parser.
+var directives = map[string]bool{
+	FileDirective:   true,
+	ExportDirective: true,
+	KeepDirective:   true,
+	UseDirective:    true,
+}
+
+// Errors returned for directives.
+var (
+	ErrUnknownDirective     = errors.New("unknown directive")
+	ErrUndefinedExport      = errors.New("exported label not defined in the file")
+	ErrUnsupportedDirective = errors.New("directive not supported by single-pass assembly")
+)
+
+// namespaces resolves the symbols of the files of a program.
+// A file starts with a .file directive and ends at the next one;
+// the statements before the first .file directive are in the global namespace.
+// The labels of a file are private unless exported, and so are its variables:
+// both are qualified with the name of the file, as in File.name,
+// following the convention of the VM translator for static variables.
+// Symbols containing a '.' are already qualified, and are global.
+type namespaces struct {
+	// labels are the labels defined in each file, mapped to whether they are exported.
+	labels map[string]map[string]bool
+
+	// global are the labels visible from every file.
+	global map[string]bool
+
+	// kept are the names of the labels kept by .keep directives in the symbol table.
+	kept map[string]bool
+}
+
+// newNamespaces collects the labels and the exports of the files of the program,
+// and adds the errors of its directives to errs.
+func newNamespaces(program *Program, errs *errorList) *namespaces {
+	n := &namespaces{
+		labels: map[string]map[string]bool{"": {}},
+		global: make(map[string]bool),
+		kept:   make(map[string]bool),
+	}
+	// exports are the .export and .keep directives naming labels, and files the file of each of them.
+	var exports []*Directive
+	var files []string
+
+	file := ""
+	for _, node := range program.Nodes {
+		switch command := node.(type) {
+		case *Directive:
+			switch command.Name {
+			case FileDirective:
+				if err := n.startFile(command); err != nil {
+					if errs.add(err) {
+						return n
+					}
+					continue
+				}
+				file = command.Argument
+			case ExportDirective, KeepDirective:
+				exports = append(exports, command)
+				files = append(files, file)
+			}
+		case *Label:
+			n.labels[file][command.Name] = false
+		}
+	}
+
+	for i, export := range exports {
+		if err := n.export(files[i], export); err != nil && errs.add(err) {
+			return n
+		}
+	}
+	n.collectGlobal()
+
+	// A label is qualified once every export of its file is known.
+	for i, export := range exports {
+		if export.Name == KeepDirective {
+			n.keep(files[i], export)
+		}
+	}
+
+	return n
+}
+
+// startFile starts the namespace of the file named by the .file directive.
+func (n *namespaces) startFile(directive *Directive) error {
+	if strings.Contains(directive.Argument, ".") {
+		return newSourceError(directive, "%s: file names can not contain '.': %w", directive, ErrInvalidSymbol)
+	}
+	if n.labels[directive.Argument] == nil {
+		n.labels[directive.Argument] = make(map[string]bool)
+	}
+
+	return nil
+}
+
+// export checks that the label named by the .export or .keep directive is defined in the file,
+// and exports it for .export.
+func (n *namespaces) export(file string, directive *Directive) error {
+	if _, ok := n.labels[file][directive.Argument]; !ok {
+		err := ErrUndefinedExport
+		if directive.Name == KeepDirective {
+			err = ErrSymbolNotFound
+		}
+		return newSourceError(directive, "%s: %w", directive, err)
+	}
+	if directive.Name == ExportDirective {
+		n.labels[file][directive.Argument] = true
+	}
+
+	return nil
+}
+
+// keep keeps the label of the file named by the .keep directive, if it is defined.
+func (n *namespaces) keep(file string, directive *Directive) {
+	if _, ok := n.labels[file][directive.Argument]; ok {
+		n.kept[n.label(file, directive.Argument)] = true
+	}
+}
+
+// collectGlobal collects the labels visible from every file.
+func (n *namespaces) collectGlobal() {
+	for file, labels := range n.labels {
+		for label, exported := range labels {
+			if !n.private(file, label, exported) {
+				n.global[label] = true
+			}
+		}
+	}
+}
+
+// private returns true if the symbol of the file is qualified with the name of the file.
+func (n *namespaces) private(file string, symbol string, exported bool) bool {
+	return file != "" && !exported && !strings.Contains(symbol, ".")
+}
+
+// label returns the name of the label defined in the file in the symbol table.
+func (n *namespaces) label(file string, name string) string {
+	if n.private(file, name, n.labels[file][name]) {
+		return file + "." + name
+	}
+
+	return name
+}
+
+// reference returns the name of the symbol used in the file in the symbol table.
+// A symbol is a label of the file, a predefined symbol, a global label,
+// or else a variable of the file.
+func (n *namespaces) reference(file string, symbol string, predefined *SymbolTable) string {
+	if _, ok := n.labels[file][symbol]; ok {
+		return n.label(file, symbol)
+	}
+	if predefined.Contains(symbol) || n.global[symbol] || !n.private(file, symbol, false) {
+		return symbol
+	}
+
+	return file + "." + symbol
+}
+
+// nextFile returns the file of the statements following the node.
+func nextFile(node Node, file string) string {
+	if directive, ok := node.(*Directive); ok && directive.Name == FileDirective {
+		return directive.Argument
+	}
+
+	return file
+}
+
+// qualify returns the A-instruction referring to its symbol as resolved in the file.
+func (a *Assembler) qualify(command *AInstruction, file string) *AInstruction {
+	if command.Symbol == "" || a.namespaces == nil {
+		return command
+	}
+
+	name := a.namespaces.reference(file, command.Symbol, a.config.predefined)
+	if name == command.Symbol {
+		return command
+	}
+
+	return &AInstruction{Position: command.Position, Symbol: name}
+}
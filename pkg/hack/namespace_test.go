@@ -0,0 +1,171 @@
+package hack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	namespacedMain = `.file Main
+@temp
+M=0
+@LOOP
+0;JMP
+(LOOP)
+@double
+0;JMP
+`
+	namespacedMath = `.file Math
+.export double
+(double)
+@temp
+M=D
+(LOOP)
+@LOOP
+0;JMP
+@R1
+@Main.temp
+`
+	namespacedCommands = namespacedMain + namespacedMath
+)
+
+func TestAssembler_Assemble_Namespaces(t *testing.T) {
+	t.Parallel()
+
+	assembler, err := NewAssembler(strings.NewReader(namespacedCommands), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]uint{
+		"Main.LOOP": 4,
+		"double":    6,
+		"Math.LOOP": 8,
+		"Main.temp": 16,
+		"Math.temp": 17,
+	}
+	got := make(map[string]uint)
+	for _, entry := range assembler.SymbolTable().Entries() {
+		if !assembler.config.IsPredefined(entry.Symbol()) {
+			got[entry.Symbol()] = entry.Address()
+		}
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAssembler_Assemble_GlobalNamespace(t *testing.T) {
+	t.Parallel()
+
+	// The labels before the first .file directive are global,
+	// but the variables of a file are private even if a global variable has the same name.
+	source := "(START)\n@x\n@START\n.file Main\n@x\n@START\n"
+
+	program, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := program.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(words, []uint16{16, 0, 17, 0}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestAssembler_Assemble_DirectiveErrors(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		err      error
+	}{
+		{"undefined export", ".file Main\n.export LOOP\n@1\n", ErrUndefinedExport},
+		{"export of another file", ".file A\n(LOOP)\n.file B\n.export LOOP\n", ErrUndefinedExport},
+		{"unknown directive", ".include lib\n", ErrUnknownDirective},
+		{"invalid argument", ".export 1X\n", ErrInvalidSymbol},
+		{"qualified file", ".file Main.asm\n", ErrInvalidSymbol},
+		{"duplicate private label", ".file Main\n(L)\n(L)\n", ErrSymbolAlreadyExists},
+		{"private and exported label", ".file A\n.export L\n(L)\n.file B\n.export L\n(L)\n", ErrSymbolAlreadyExists},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			assembler, err := NewAssembler(strings.NewReader(d.source), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if !errors.Is(err, d.err) {
+				t.Errorf("expected %v, got %v", d.err, err)
+			}
+		})
+	}
+}
+
+func TestAssembler_AssembleSinglePass_Directive(t *testing.T) {
+	t.Parallel()
+
+	assembler, err := NewAssembler(strings.NewReader(namespacedCommands), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.AssembleSinglePass()
+	if !errors.Is(err, ErrUnsupportedDirective) {
+		t.Errorf("expected ErrUnsupportedDirective, got %v", err)
+	}
+}
+
+func TestLinker_Link_Namespaces(t *testing.T) {
+	t.Parallel()
+
+	writer := &bytes.Buffer{}
+	assembler, err := NewAssembler(strings.NewReader(namespacedCommands), writer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := assembleObject(t, "main", namespacedMain)
+	if _, ok := main.Exports["LOOP"]; ok {
+		t.Error("expected private label LOOP not to be exported")
+	}
+
+	linked := &bytes.Buffer{}
+	linker, err := NewLinker(linked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = linker.Link(main, assembleObject(t, "math", namespacedMath))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(linked.String(), writer.String()); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,241 @@
+package hack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Identification of the object file format.
+const (
+	ObjectFormat  = "hack-object"
+	ObjectVersion = 1
+)
+
+// Object is a module assembled separately into relocatable machine code.
+// Its instructions are placed at address 0, and moved by the Linker.
+type Object struct {
+	// Name identifies the object in the errors of the Linker. It is not written.
+	Name string `json:"-"`
+
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+
+	// Words is the machine code of the module.
+	// The words of relocations hold the address of a label relative to the module,
+	// and the words of imports hold 0.
+	Words []uint16 `json:"words"`
+
+	// Exports maps the global labels of the module to their addresses relative to the module.
+	// The private labels of the files of the module are not exported.
+	Exports map[string]uint `json:"exports,omitempty"`
+
+	// Relocations are the addresses of the A-instructions referring to the labels of the module.
+	Relocations []uint `json:"relocations,omitempty"`
+
+	// Imports are the symbols used but not defined by the module, in the order of their first use.
+	// The Linker binds them to the labels exported by the other modules,
+	// or allocates them as variables.
+	Imports []Import `json:"imports,omitempty"`
+
+	// Uses are the routines of the standard library used by the module with .use directives,
+	// in the order of their first use. The Linker adds each of them once, after the objects.
+	Uses []string `json:"uses,omitempty"`
+}
+
+// Import is a symbol used but not defined by a module.
+type Import struct {
+	Symbol string `json:"symbol"`
+
+	// Variable is the name of the variable allocated if no module exports Symbol,
+	// qualified with the name of the file using it, or empty if it is Symbol itself.
+	Variable string `json:"variable,omitempty"`
+
+	// Addresses are the addresses of the A-instructions referring to the symbol.
+	Addresses []uint `json:"addresses"`
+}
+
+// ErrInvalidObject is returned when an object can not be read.
+var ErrInvalidObject = errors.New("invalid object")
+
+// ReadObject reads an object written by Object.Write.
+func ReadObject(r io.Reader) (*Object, error) {
+	var o Object
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&o)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode object: %s: %w", err.Error(), ErrInvalidObject)
+	}
+
+	err = o.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &o, nil
+}
+
+// Validate returns ErrInvalidObject if the object is not consistent.
+func (o *Object) Validate() error {
+	if o.Format != ObjectFormat || o.Version != ObjectVersion {
+		return fmt.Errorf("unsupported format %s version %d: %w", o.Format, o.Version, ErrInvalidObject)
+	}
+
+	err := o.validateRelocations()
+	if err != nil {
+		return err
+	}
+
+	return o.validateSections()
+}
+
+// validateRelocations checks that the relocations and the imports refer to words of the object.
+func (o *Object) validateRelocations() error {
+	size := uint(len(o.Words))
+	for _, address := range o.Relocations {
+		if address >= size {
+			return fmt.Errorf("relocation at %d: %w", address, ErrInvalidObject)
+		}
+	}
+	for _, imp := range o.Imports {
+		for _, address := range imp.Addresses {
+			if address >= size {
+				return fmt.Errorf("import %s at %d: %w", imp.Symbol, address, ErrInvalidObject)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateSections checks the symbols of the exports and the imports, and the routines used.
+func (o *Object) validateSections() error {
+	for symbol, address := range o.Exports {
+		if !isSymbol(symbol) || address > uint(len(o.Words)) {
+			return fmt.Errorf("export %s=%d: %w", symbol, address, ErrInvalidObject)
+		}
+	}
+	for _, imp := range o.Imports {
+		if !isSymbol(imp.Symbol) || imp.Variable != "" && !isSymbol(imp.Variable) {
+			return fmt.Errorf("import %s: %w", imp.Symbol, ErrInvalidObject)
+		}
+	}
+	for _, routine := range o.Uses {
+		if _, ok := libraryFiles[routine]; !ok {
+			return fmt.Errorf("use %s: %w", routine, ErrInvalidObject)
+		}
+	}
+
+	return nil
+}
+
+// Write writes the object as JSON.
+func (o *Object) Write(w io.Writer) error {
+	return json.NewEncoder(w).Encode(o)
+}
+
+// AssembleObject is like Assemble, but writes the program as a relocatable Object
+// to be linked with other modules by a Linker.
+// Predefined symbols are resolved, and the global labels of the program are exported.
+// Symbols neither predefined nor defined by the program are imported,
+// instead of being allocated as variables.
+// Directives are supported, so that a module can keep the labels of its files private.
+// The routines of the standard library used by the program are not included,
+// but recorded in the object, so that the Linker includes each of them once.
+func (a *Assembler) AssembleObject() error {
+	return a.AssembleObjectContext(context.Background())
+}
+
+// AssembleObjectContext is like AssembleObject, but stops with the error of ctx when ctx is done.
+func (a *Assembler) AssembleObjectContext(ctx context.Context) error {
+	program, err := parse(ctx, a.parser, a.config.maxErrors)
+	if err != nil {
+		return err
+	}
+
+	object, err := a.assembleObject(program)
+	if err != nil {
+		return err
+	}
+	a.size = len(object.Words)
+
+	return object.Write(a.w)
+}
+
+// assembleObject translates the program into a relocatable Object.
+func (a *Assembler) assembleObject(program *Program) (*Object, error) {
+	a.reset()
+	errs := errorList{max: a.config.maxErrors}
+
+	uses := usedRoutines(program, &errs)
+	count := a.createSymbolTable(program, &errs)
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
+	}
+
+	object := &Object{
+		Format:  ObjectFormat,
+		Version: ObjectVersion,
+		Words:   make([]uint16, 0, count),
+		Exports: make(map[string]uint),
+		Uses:    uses,
+	}
+	// imports maps the variable names of the imports to their index.
+	imports := make(map[string]int)
+
+	file := ""
+	for _, node := range program.Nodes {
+		address := uint(len(object.Words))
+		var word uint16
+		var err error
+
+		file = nextFile(node, file)
+		switch command := node.(type) {
+		case *Label:
+			if name := a.namespaces.label(file, command.Name); name == command.Name {
+				object.Exports[name] = address
+			}
+			continue
+		case *AInstruction:
+			qualified := a.qualify(command, file)
+			switch {
+			case command.Symbol == "" || a.config.predefined.Contains(qualified.Symbol):
+				word, err = a.assembleACommand(qualified)
+			case a.symbolTable.Contains(qualified.Symbol):
+				word, err = a.assembleACommand(qualified)
+				object.Relocations = append(object.Relocations, address)
+			default:
+				i, ok := imports[qualified.Symbol]
+				if !ok {
+					i = len(object.Imports)
+					imports[qualified.Symbol] = i
+					imp := Import{Symbol: command.Symbol}
+					if qualified.Symbol != command.Symbol {
+						imp.Variable = qualified.Symbol
+					}
+					object.Imports = append(object.Imports, imp)
+				}
+				object.Imports[i].Addresses = append(object.Imports[i].Addresses, address)
+			}
+		case *CInstruction:
+			word, err = a.assembleCCommand(command)
+		default:
+			continue
+		}
+		if err != nil && errs.add(newSourceError(node, "%s: %w", node, err)) {
+			break
+		}
+
+		object.Words = append(object.Words, word)
+	}
+
+	if len(errs.errs) > 0 {
+		return nil, errs.err()
+	}
+
+	return object, nil
+}
@@ -0,0 +1,218 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Option configures the assemblers created by NewAssembler or NewConfig.
+type Option func(*options)
+
+// options are the settings of a Config before it is created.
+type options struct {
+	profile       Profile
+	memoryMap     *MemoryMap
+	code          InstructionSet
+	symbols       map[string]uint
+	variableBase  uint
+	format        Format
+	strict        bool
+	strip         bool
+	maxErrors     int
+	maxLineLength int
+	maxFileSize   int64
+	tabWidth      int
+}
+
+// defaultOptions returns the settings of the standard Hack computer.
+func defaultOptions() options {
+	return options{
+		profile:       StandardProfile(),
+		code:          NewCode(),
+		variableBase:  initialNextAddress,
+		format:        FormatBinary,
+		maxErrors:     1,
+		maxLineLength: DefaultMaxLineLength,
+		tabWidth:      DefaultTabWidth,
+	}
+}
+
+// ErrInvalidOption is returned when an option can not be applied.
+var ErrInvalidOption = errors.New("invalid option")
+
+// WithProfile selects the Hack computer build described by the profile.
+// The standard profile is used by default.
+func WithProfile(profile Profile) Option {
+	return func(o *options) {
+		o.profile = profile
+	}
+}
+
+// WithMemoryMap overrides the memory map of the profile.
+// The SCREEN and KBD symbols are bound to the addresses of the memory map.
+func WithMemoryMap(m MemoryMap) Option {
+	return func(o *options) {
+		o.memoryMap = &m
+	}
+}
+
+// WithInstructionSet sets the instruction set used to parse and translate C commands.
+// The instruction set must not be modified while it is in use.
+func WithInstructionSet(is InstructionSet) Option {
+	return func(o *options) {
+		o.code = is
+	}
+}
+
+// WithSymbols predefines the symbols in addition to the symbols of the profile.
+// The symbols override the symbols of the profile with the same name.
+func WithSymbols(symbols map[string]uint) Option {
+	return func(o *options) {
+		if o.symbols == nil {
+			o.symbols = make(map[string]uint, len(symbols))
+		}
+		for symbol, address := range symbols {
+			o.symbols[symbol] = address
+		}
+	}
+}
+
+// WithVariableBase sets the address of the first variable, 16 by default.
+func WithVariableBase(address uint) Option {
+	return func(o *options) {
+		o.variableBase = address
+	}
+}
+
+// WithFormat sets the format of the machine code written by the assemblers.
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithStrict makes warnings errors, so that the assembly of a program
+// allocating variables in the I/O regions fails.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithStrip makes Assemble remove the labeled routines unreachable from the entry point of the program,
+// such as the unused routines of an included library.
+// The number of words removed is returned by Assembler.Stripped.
+// Labels kept with the .keep directive are never removed.
+// Single-pass assembly and objects are not stripped.
+func WithStrip(strip bool) Option {
+	return func(o *options) {
+		o.strip = strip
+	}
+}
+
+// WithMaxErrors sets the number of errors reported before the assembly stops, 1 by default.
+// A number less than 1 reports every error.
+// Errors are reported together, joined with errors.Join.
+func WithMaxErrors(n int) Option {
+	return func(o *options) {
+		o.maxErrors = n
+	}
+}
+
+// WithMaxLineLength sets the maximum length of a source line in bytes, DefaultMaxLineLength by default.
+// Longer lines are reported as errors wrapping ErrLineTooLong.
+func WithMaxLineLength(n int) Option {
+	return func(o *options) {
+		o.maxLineLength = n
+	}
+}
+
+// WithMaxFileSize sets the maximum size of the source in bytes, unlimited by default.
+// A larger source is reported as an error wrapping ErrFileTooLarge.
+// A size less than 1 removes the limit.
+func WithMaxFileSize(n int64) Option {
+	return func(o *options) {
+		o.maxFileSize = n
+	}
+}
+
+// WithTabWidth sets the distance between tab stops used to compute the columns
+// of diagnostics, DefaultTabWidth by default.
+func WithTabWidth(n int) Option {
+	return func(o *options) {
+		o.tabWidth = n
+	}
+}
+
+// Format is the format of the machine code written by an assembler.
+type Format int
+
+const (
+	// FormatBinary writes a line of 16 binary digits per instruction, as in .hack files.
+	FormatBinary Format = iota
+	// FormatHex writes a line of 4 hexadecimal digits per instruction.
+	FormatHex
+	// FormatRaw writes 2 bytes per instruction, most significant byte first.
+	FormatRaw
+)
+
+var formatNames = []string{"binary", "hex", "raw"}
+
+func (f Format) String() string {
+	if f < 0 || int(f) >= len(formatNames) {
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+	return formatNames[f]
+}
+
+// ErrUnknownFormat is returned when a format name is not known.
+var ErrUnknownFormat = errors.New("unknown format")
+
+// ParseFormat returns the format with the given name: binary, hex or raw.
+func ParseFormat(name string) (Format, error) {
+	for i, formatName := range formatNames {
+		if name == formatName {
+			return Format(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%s: %w", name, ErrUnknownFormat)
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendWord appends the word in the format to dst.
+func (f Format) appendWord(dst []byte, word uint16) []byte {
+	switch f {
+	case FormatHex:
+		for shift := 12; shift >= 0; shift -= 4 {
+			dst = append(dst, hexDigits[word>>shift&0xf])
+		}
+		return append(dst, '\n')
+	case FormatRaw:
+		return append(dst, byte(word>>8), byte(word))
+	default:
+		return appendBinary(dst, word)
+	}
+}
+
+// errorList collects the errors of an assembly up to a maximum number.
+type errorList struct {
+	errs []error
+	max  int
+}
+
+// add adds the error, and returns true if the assembly must stop.
+func (l *errorList) add(err error) bool {
+	l.errs = append(l.errs, err)
+	return l.max > 0 && len(l.errs) >= l.max
+}
+
+// err returns the error collected, the errors joined if there are several, or nil.
+func (l *errorList) err() error {
+	if len(l.errs) == 1 {
+		return l.errs[0]
+	}
+
+	return errors.Join(l.errs...)
+}
@@ -0,0 +1,189 @@
+package hack
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestNewAssembler_Options(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+		opts     []Option
+		output   string
+	}{
+		{
+			testCase: "variable base",
+			asm:      "@a\n@b\n",
+			opts:     []Option{WithVariableBase(256)},
+			output:   "0000000100000000\n0000000100000001\n",
+		},
+		{
+			testCase: "symbols",
+			asm:      "@LED\n@SP\n",
+			opts:     []Option{WithSymbols(map[string]uint{"LED": 24577})},
+			output:   "0110000000000001\n0000000000000000\n",
+		},
+		{
+			testCase: "bare profile",
+			asm:      "@SP\n",
+			opts:     []Option{WithProfile(BareProfile())},
+			output:   "0000000000010000\n",
+		},
+		{
+			testCase: "hex format",
+			asm:      "@SP\nM=M+1\n",
+			opts:     []Option{WithFormat(FormatHex)},
+			output:   "0000\nfdc8\n",
+		},
+		{
+			testCase: "raw format",
+			asm:      "@1\nM=M+1\n",
+			opts:     []Option{WithFormat(FormatRaw)},
+			output:   "\x00\x01\xfd\xc8",
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &bytes.Buffer{}
+			assembler, err := NewAssembler(strings.NewReader(d.asm), writer, d.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(writer.String(), d.output); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestNewAssembler_InvalidOptions(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		opts     []Option
+		err      error
+	}{
+		{"variable base beyond RAM", []Option{WithVariableBase(ramSize)}, ErrInvalidOption},
+		{"unknown format", []Option{WithFormat(Format(9))}, ErrUnknownFormat},
+		{"invalid symbol", []Option{WithSymbols(map[string]uint{"1X": 0})}, ErrInvalidProfile},
+		{"invalid memory map", []Option{WithMemoryMap(MemoryMap{})}, ErrInvalidMemoryMap},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewAssembler(strings.NewReader(""), &bytes.Buffer{}, d.opts...)
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestNewAssembler_WithStrict(t *testing.T) {
+	t.Parallel()
+
+	small := MemoryMap{ROMSize: 5, RAMSize: 20, ScreenAddress: 17, KBDAddress: 19}
+
+	assembler, err := NewAssembler(strings.NewReader("@a\n@b\n"), &bytes.Buffer{}, WithMemoryMap(small), WithStrict(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if !errors.Is(err, ErrScreenVariable) {
+		t.Errorf("expected ErrScreenVariable, got %v", err)
+	}
+	if len(assembler.Warnings()) != 0 {
+		t.Errorf("expected no warnings, got %v", assembler.Warnings())
+	}
+}
+
+func TestNewAssembler_WithMaxErrors(t *testing.T) {
+	t.Parallel()
+
+	asm := "@1\nD=X\n(L)\n(L)\nD=Y\n0;JMP\nD;JXX\n"
+
+	data := []struct {
+		testCase  string
+		maxErrors int
+		lines     []uint
+	}{
+		{"default", 1, []uint{2}},
+		{"two", 2, []uint{2, 5}},
+		{"unlimited", 0, []uint{2, 5, 7}},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			assembler, err := NewAssembler(strings.NewReader(asm), &bytes.Buffer{}, WithMaxErrors(d.maxErrors))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+
+			var lines []uint
+			if joined, ok := err.(interface{ Unwrap() []error }); ok {
+				for _, e := range joined.Unwrap() {
+					var sourceErr *SourceError
+					if errors.As(e, &sourceErr) {
+						lines = append(lines, sourceErr.Line)
+					}
+				}
+			} else {
+				var sourceErr *SourceError
+				if errors.As(err, &sourceErr) {
+					lines = append(lines, sourceErr.Line)
+				}
+			}
+
+			if diff := cmp.Diff(lines, d.lines); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []Format{FormatBinary, FormatHex, FormatRaw} {
+		got, err := ParseFormat(format.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != format {
+			t.Errorf("expected %s, got %s", format, got)
+		}
+	}
+
+	_, err := ParseFormat("octal")
+	if !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
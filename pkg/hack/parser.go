@@ -5,62 +5,82 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"regexp"
+	"strconv"
 	"strings"
 )
 
-func removeComment(line string) string {
-	return strings.Split(line, "//")[0]
-}
+// StatementSeparator separates multiple statements written on one line.
+// It is distinct from the single ';' that introduces a jump mnemonic.
+const StatementSeparator = ";;"
 
 // This is a parser for the Hack assembly language.
 // - It parses the assembly language into its individual components.
 // - It removes comments and whitespace.
-// - It tracks the current line number.
+// - It splits lines holding several statements into individual commands.
+// - It decodes UTF-16 sources, skips byte order marks and accepts "\n", "\r\n" and "\r" line endings.
+// - It tracks the current line number, source line and column.
 // - It also keeps track of the current command type.
 type Parser struct {
 	r               io.Reader
 	scanner         *bufio.Scanner
 	hasMoreCommands bool
 	lineNumber      uint
+	sourceLine      uint
 
-	regComment   *regexp.Regexp
-	regSpaceLine *regexp.Regexp
-
-	regACommandSymbol *regexp.Regexp
+	current      statement
+	pending      []statement
+	statements   []statement
+	keepComments bool
 
-	regLCommandSymbol *regexp.Regexp
+	instructionSet InstructionSet
 
-	regDest *regexp.Regexp
-	regComp *regexp.Regexp
-	regJump *regexp.Regexp
+	maxLineLength int
+	maxFileSize   int64
+	tabWidth      int
+	err           error
 }
 
 type CommandType int
 
+// SourceError is an error annotated with the source position of the statement that caused it.
+type SourceError struct {
+	Line   uint
+	Column uint
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Column, e.Err.Error())
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// Errorf returns a SourceError positioned at the current command.
+func (p *Parser) Errorf(format string, a ...any) error {
+	return &SourceError{Line: p.sourceLine, Column: p.current.column, Err: fmt.Errorf(format, a...)}
+}
+
 const (
 	ACommand CommandType = iota
 	CCommand
 	LCommand
+	DirectiveCommand
+
+	// commentCommand is a comment, only returned by parsers keeping comments.
+	commentCommand
 )
 
 // NewParser creates a new parser.
 func NewParser(r io.Reader) *Parser {
 	var p Parser
 
+	p.maxLineLength = DefaultMaxLineLength
+	p.tabWidth = DefaultTabWidth
 	p.Reset(r)
 
-	p.regComment = regexp.MustCompile(`//.*$`)
-	p.regSpaceLine = regexp.MustCompile(`^\s*$`)
-
-	p.regACommandSymbol = regexp.MustCompile(`^\s*@([0-9a-z-A-Z_.$:][0-9a-z-A-Z_.$:]*)`)
-	p.regLCommandSymbol = regexp.MustCompile(`^\s*\(([a-z-A-Z_.$:][0-9a-z-A-Z_.$:]*)\)`)
-
-	p.regDest = regexp.MustCompile(`^\s*(M|D|MD|A|AM|AD|AMD)=`)
-	p.regComp = regexp.MustCompile(
-		`^(D\+1|A\+1|D-1|A-1|D\+A|D-A|A-D|D&A|D\|A|M\+1|M-1|D\+M|D-M|M-D|D\|M|D&M|0|1|-1|D|A|!D|!A|-D|-A|M|M|!M|-M)$`,
-	)
-	p.regJump = regexp.MustCompile(`;(JGT|JEQ|JGE|JLT|JNE|JLE|JMP)`)
+	p.instructionSet = NewCode()
 
 	return &p
 }
@@ -68,23 +88,93 @@ func NewParser(r io.Reader) *Parser {
 // Advance advances the parser to the next command
 // Returns true if there are more commands to parse.
 func (p *Parser) Advance() bool {
-	for p.scanner.Scan() {
-		line := p.scanner.Text()
-		line = p.regComment.ReplaceAllString(line, "")
-		if p.regSpaceLine.MatchString(line) {
-			continue
+	for len(p.pending) == 0 {
+		// The last line before a read error is cut short, so it is not parsed.
+		if !p.scanner.Scan() || p.scanner.Err() != nil {
+			return p.stop(p.scanError(p.scanner.Err()))
 		}
+		p.sourceLine++
 
-		if p.CommandType() != LCommand {
-			p.lineNumber++
+		line := p.scanner.Text()
+		if len(line) > p.maxLineLength {
+			return p.stop(p.lineTooLong())
 		}
+		p.statements = appendStatements(p.statements[:0], line, p.keepComments, p.tabWidth)
+		p.pending = p.statements
+	}
+
+	p.current = p.pending[0]
+	p.pending = p.pending[1:]
 
-		return true
+	if p.current.kind == ACommand || p.current.kind == CCommand {
+		p.lineNumber++
 	}
+
+	return true
+}
+
+// Errors returned when the source exceeds the limits of the parser.
+var (
+	ErrLineTooLong  = errors.New("line too long")
+	ErrFileTooLarge = errors.New("file too large")
+)
+
+// DefaultMaxLineLength is the default maximum length of a source line in bytes.
+const DefaultMaxLineLength = bufio.MaxScanTokenSize
+
+// Err returns the error that stopped Advance before the end of the source, or nil.
+// Read errors and lines longer than the limit are returned as SourceError
+// positioned at the line being read.
+func (p *Parser) Err() error {
+	return p.err
+}
+
+// scanError positions an error of the scanner at the line being read.
+func (p *Parser) scanError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, bufio.ErrTooLong):
+		p.sourceLine++
+		return p.lineTooLong()
+	default:
+		return &SourceError{Line: p.sourceLine + 1, Column: 1, Err: fmt.Errorf("could not read source: %w", err)}
+	}
+}
+
+// lineTooLong returns the error of the current source line being too long.
+func (p *Parser) lineTooLong() error {
+	return &SourceError{
+		Line:   p.sourceLine,
+		Column: uint(p.maxLineLength + 1),
+		Err:    fmt.Errorf("longer than %d bytes: %w", p.maxLineLength, ErrLineTooLong),
+	}
+}
+
+// SetLimits sets the maximum length in bytes of a source line,
+// and the maximum size in bytes of the source if maxFileSize is greater than 0.
+// If no line was read yet, the limits also apply to the current source.
+func (p *Parser) SetLimits(maxLineLength int, maxFileSize int64) {
+	p.maxLineLength = maxLineLength
+	p.maxFileSize = maxFileSize
+	if p.sourceLine == 0 {
+		p.Reset(p.r)
+	}
+}
+
+// stop ends the parsing with the error, which is nil at the end of the source.
+func (p *Parser) stop(err error) bool {
+	p.current = lexStatement("", 0)
 	p.hasMoreCommands = false
+	p.err = err
 	return false
 }
 
+// SetTabWidth sets the distance between tab stops used to compute the columns of commands.
+func (p *Parser) SetTabWidth(tabWidth int) {
+	p.tabWidth = tabWidth
+}
+
 // HasMoreCommands returns true if there are more commands to parse.
 func (p *Parser) HasMoreCommands() bool {
 	return p.hasMoreCommands
@@ -92,18 +182,12 @@ func (p *Parser) HasMoreCommands() bool {
 
 // Command returns the current command.
 func (p *Parser) Command() string {
-	return p.scanner.Text()
+	return p.current.text
 }
 
 // CommandType returns the type of the current command.
 func (p *Parser) CommandType() CommandType {
-	if regexp.MustCompile(`^\s*@`).MatchString(p.scanner.Text()) {
-		return ACommand
-	}
-	if regexp.MustCompile(`^\s*\(`).MatchString(p.scanner.Text()) {
-		return LCommand
-	}
-	return CCommand
+	return p.current.kind
 }
 
 // ErrNonAorLCommand is returned when the symbol command is called on a non-A or non-L command.
@@ -111,19 +195,16 @@ var ErrNonAorLCommand = errors.New("Dest called on non L or A command")
 
 // Symbol returns the symbol of the current A or L command.
 func (p *Parser) Symbol() (string, error) {
-	if p.CommandType() == ACommand {
-		if p.regACommandSymbol.MatchString(p.scanner.Text()) {
-			return p.regACommandSymbol.FindStringSubmatch(p.scanner.Text())[1], nil
-		}
+	if (p.current.kind != ACommand && p.current.kind != LCommand) || p.current.symbol == "" {
+		return "", ErrNonAorLCommand
 	}
 
-	if p.CommandType() == LCommand {
-		if p.regLCommandSymbol.MatchString(p.scanner.Text()) {
-			return p.regLCommandSymbol.FindStringSubmatch(p.scanner.Text())[1], nil
-		}
-	}
+	return p.current.symbol, nil
+}
 
-	return "", ErrNonAorLCommand
+// SetInstructionSet sets the instruction set used to recognize the mnemonics of C commands.
+func (p *Parser) SetInstructionSet(is InstructionSet) {
+	p.instructionSet = is
 }
 
 var ErrNonCCommand = errors.New("Dest called on non-C command")
@@ -134,14 +215,23 @@ func (p *Parser) Dest() (string, error) {
 		return "", ErrNonCCommand
 	}
 
-	if p.regDest.MatchString(p.scanner.Text()) {
-		return p.regDest.FindStringSubmatch(p.scanner.Text())[1], nil
+	dest := p.current.dest
+	if dest == "" {
+		return "", nil
+	}
+	if _, err := p.instructionSet.Dest(dest); err != nil {
+		return "", fmt.Errorf("%s: %w", dest, ErrInvalidDestCommand)
 	}
 
-	return "", nil
+	return dest, nil
 }
 
-var ErrInvalidCompCommand = errors.New("invalid comp")
+// Errors returned when a C command has a mnemonic unknown to the instruction set.
+var (
+	ErrInvalidDestCommand = errors.New("invalid dest")
+	ErrInvalidCompCommand = errors.New("invalid comp")
+	ErrInvalidJumpCommand = errors.New("invalid jump")
+)
 
 // Comp returns the comp command of the current C command.
 func (p *Parser) Comp() (string, error) {
@@ -149,16 +239,12 @@ func (p *Parser) Comp() (string, error) {
 		return "", ErrNonCCommand
 	}
 
-	command := removeComment(p.scanner.Text())
-	command = p.regDest.ReplaceAllString(command, "")
-	command = p.regJump.ReplaceAllString(command, "")
-	command = strings.TrimSpace(command)
-
-	if p.regComp.MatchString(command) {
-		return p.regComp.FindStringSubmatch(command)[1], nil
+	comp := p.current.comp
+	if _, err := p.instructionSet.Comp(comp); err != nil {
+		return "", fmt.Errorf("%s: %w", comp, ErrInvalidCompCommand)
 	}
 
-	return "", fmt.Errorf("%s: %w", command, ErrInvalidCompCommand)
+	return comp, nil
 }
 
 // Jump returns the jump command of the current C command.
@@ -167,11 +253,79 @@ func (p *Parser) Jump() (string, error) {
 		return "", ErrNonCCommand
 	}
 
-	if p.regJump.MatchString(p.scanner.Text()) {
-		return p.regJump.FindStringSubmatch(removeComment(p.scanner.Text()))[1], nil
+	jump := p.current.jump
+	if jump == "" {
+		return "", nil
+	}
+	if _, err := p.instructionSet.Jump(jump); err != nil {
+		return "", fmt.Errorf("%s: %w", jump, ErrInvalidJumpCommand)
+	}
+
+	return jump, nil
+}
+
+// Node returns the current command as a node of a Program.
+func (p *Parser) Node() (Node, error) {
+	pos := Position{Line: p.sourceLine, Column: p.current.column}
+
+	switch p.CommandType() {
+	case ACommand:
+		symbol, err := p.Symbol()
+		if err != nil {
+			return nil, p.Errorf("%s: %w", p.Command(), err)
+		}
+		if isNumber(symbol) {
+			value, err := strconv.ParseUint(symbol, 10, 64)
+			if err != nil {
+				return nil, p.Errorf("%s: %w", p.Command(), ErrAddressOutOfRange)
+			}
+			return &AInstruction{Position: pos, Value: uint(value)}, nil
+		}
+		if !isSymbol(symbol) {
+			return nil, p.Errorf("%s: %w", p.Command(), ErrInvalidSymbol)
+		}
+		return &AInstruction{Position: pos, Symbol: symbol}, nil
+	case LCommand:
+		symbol, err := p.Symbol()
+		if err != nil {
+			return nil, p.Errorf("%s: %w", p.Command(), err)
+		}
+		return &Label{Position: pos, Name: symbol}, nil
+	case CCommand:
+		// The errors of the mnemonics already start with the mnemonic.
+		dest, err := p.Dest()
+		if err != nil {
+			return nil, p.Errorf("%w", err)
+		}
+		comp, err := p.Comp()
+		if err != nil {
+			return nil, p.Errorf("%w", err)
+		}
+		jump, err := p.Jump()
+		if err != nil {
+			return nil, p.Errorf("%w", err)
+		}
+		return &CInstruction{Position: pos, Dest: dest, Comp: comp, Jump: jump}, nil
+	case DirectiveCommand:
+		return p.directive(pos)
+	case commentCommand:
+		return &Comment{Position: pos, Text: strings.TrimSpace(strings.TrimPrefix(p.Command(), "//"))}, nil
 	}
 
-	return "", nil
+	return nil, p.Errorf("%s: %w", p.Command(), ErrInvalidCommand)
+}
+
+// directive returns the current directive command as a node.
+func (p *Parser) directive(pos Position) (Node, error) {
+	name, argument := p.current.symbol, p.current.argument
+	if !directives[name] {
+		return nil, p.Errorf("%s: %w", p.Command(), ErrUnknownDirective)
+	}
+	if !isSymbol(argument) {
+		return nil, p.Errorf("%s: %w", p.Command(), ErrInvalidSymbol)
+	}
+
+	return &Directive{Position: pos, Name: name, Argument: argument}, nil
 }
 
 // LineNumber returns the current line number of the parser.
@@ -179,10 +333,61 @@ func (p *Parser) LineNumber() uint {
 	return p.lineNumber
 }
 
+// SourceLine returns the 1-based source line of the current command.
+func (p *Parser) SourceLine() uint {
+	return p.sourceLine
+}
+
+// Column returns the 1-based column of the current command in its source line.
+func (p *Parser) Column() uint {
+	return p.current.column
+}
+
+const initialBufferSize = 4096
+
+// limitedReader reads from r until more than limit bytes are read, and then fails.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+}
+
+func (l *limitedReader) Read(b []byte) (int, error) {
+	if l.remaining <= 0 {
+		// The source is larger than the limit only if there is anything left to read.
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			return 0, fmt.Errorf("larger than %d bytes: %w", l.limit, ErrFileTooLarge)
+		}
+		return 0, err
+	}
+
+	if int64(len(b)) > l.remaining {
+		b = b[:l.remaining]
+	}
+	n, err := l.r.Read(b)
+	l.remaining -= int64(n)
+
+	return n, err
+}
+
 // Reset resets the parser to read from the given reader.
 func (p *Parser) Reset(r io.Reader) {
 	p.r = r
-	p.scanner = bufio.NewScanner(r)
+	if p.maxFileSize > 0 {
+		r = &limitedReader{r: r, remaining: p.maxFileSize, limit: p.maxFileSize}
+	}
+	p.scanner = bufio.NewScanner(newSourceReader(r))
+	p.scanner.Split(scanLines)
+	// The buffer holds the longest line with its line ending, so that longer lines are detected.
+	maxBuffer := p.maxLineLength + len("\r\n")
+	p.scanner.Buffer(make([]byte, 0, min(maxBuffer, initialBufferSize)), maxBuffer)
+	p.err = nil
 	p.hasMoreCommands = true
 	p.lineNumber = 0
+	p.sourceLine = 0
+	p.current = lexStatement("", 0)
+	p.pending = nil
+	p.statements = nil
 }
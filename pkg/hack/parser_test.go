@@ -1,6 +1,7 @@
 package hack
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -156,3 +157,67 @@ func TestParser_Jump(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+func TestParser_MultipleStatements(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(strings.NewReader("(LOOP) @i\n  @SP;;AM=M+1 // push\n(A)(B)D;JMP\n"))
+
+	type position struct {
+		Command    string
+		SourceLine uint
+		Column     uint
+	}
+
+	var got []position
+	for p.Advance() {
+		got = append(got, position{p.Command(), p.SourceLine(), p.Column()})
+	}
+
+	want := []position{
+		{"(LOOP)", 1, 1},
+		{"@i", 1, 8},
+		{"@SP", 2, 3},
+		{"AM=M+1", 2, 8},
+		{"(A)", 3, 1},
+		{"(B)", 3, 4},
+		{"D;JMP", 3, 7},
+	}
+
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParser_Node_ATrailingText(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+		err      error
+	}{
+		{"semicolon", "@x;D=M\n", ErrInvalidSymbol},
+		{"space", "@x D=M\n", ErrInvalidSymbol},
+		{"tab", "@x\tD=M\n", ErrInvalidSymbol},
+		{"number", "@12 D=M\n", ErrInvalidSymbol},
+		{"trailing blanks", "@x \t\n", nil},
+		{"comment", "@x // D=M\n", nil},
+		{"separator", "@x ;; D=M\n", nil},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			p := NewParser(strings.NewReader(d.asm))
+			p.Advance()
+
+			_, err := p.Node()
+			if !errors.Is(err, d.err) {
+				t.Errorf("expected %v, got %v", d.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package hack
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Names of the built-in profiles.
+const (
+	// StandardProfileName is the nand2tetris computer with SP, LCL, ARG, THIS, THAT,
+	// R0-R15, SCREEN and KBD predefined.
+	StandardProfileName = "standard"
+	// BareProfileName is the nand2tetris computer without any predefined symbol.
+	BareProfileName = "bare"
+)
+
+// Profile describes a Hack computer build: its memory map and the symbols predefined
+// for programs written for it.
+// The predefined symbols are the symbols of the Base profile, bound to the
+// addresses of the MemoryMap, extended with Symbols.
+type Profile struct {
+	Name      string          `json:"name"      toml:"name"`
+	Base      string          `json:"base"      toml:"base"`
+	MemoryMap MemoryMap       `json:"memoryMap" toml:"memoryMap"`
+	Symbols   map[string]uint `json:"symbols"   toml:"symbols"`
+}
+
+// StandardProfile returns the profile of the standard nand2tetris computer.
+func StandardProfile() Profile {
+	return Profile{
+		Name:      StandardProfileName,
+		Base:      StandardProfileName,
+		MemoryMap: StandardMemoryMap(),
+		Symbols:   nil,
+	}
+}
+
+// BareProfile returns the profile of the standard nand2tetris computer without
+// any predefined symbol.
+func BareProfile() Profile {
+	return Profile{
+		Name:      BareProfileName,
+		Base:      BareProfileName,
+		MemoryMap: StandardMemoryMap(),
+		Symbols:   nil,
+	}
+}
+
+// ErrUnknownProfile is returned when a profile name is not a built-in profile.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// LookupProfile returns the built-in profile with the given name.
+func LookupProfile(name string) (Profile, error) {
+	switch name {
+	case StandardProfileName:
+		return StandardProfile(), nil
+	case BareProfileName:
+		return BareProfile(), nil
+	}
+
+	return Profile{}, fmt.Errorf("%s: %w", name, ErrUnknownProfile)
+}
+
+// ErrInvalidProfile is returned when a profile can not be loaded.
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// LoadProfile reads a JSON profile such as:
+//
+//	{
+//	  "name": "io-board",
+//	  "base": "standard",
+//	  "memoryMap": {"ramSize": 24580},
+//	  "symbols": {"LED": 24577, "SWITCH": 24578}
+//	}
+//
+// An omitted base defaults to the standard profile, and omitted memory map
+// fields default to the memory map of the standard computer.
+func LoadProfile(r io.Reader) (Profile, error) {
+	var profile Profile
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&profile)
+	if err != nil {
+		return profile, fmt.Errorf("could not decode profile: %s: %w", err.Error(), ErrInvalidProfile)
+	}
+
+	return profile.complete()
+}
+
+// LoadProfileTOML is like LoadProfile, but reads a TOML profile such as:
+//
+//	name = "io-board"
+//	base = "standard"
+//
+//	[memoryMap]
+//	ramSize = 24580
+//
+//	[symbols]
+//	LED = 24577
+//	SWITCH = 24578
+func LoadProfileTOML(r io.Reader) (Profile, error) {
+	var profile Profile
+
+	metadata, err := toml.NewDecoder(r).Decode(&profile)
+	if err != nil {
+		return profile, fmt.Errorf("could not decode profile: %s: %w", err.Error(), ErrInvalidProfile)
+	}
+	if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+		return profile, fmt.Errorf("could not decode profile: unknown key %s: %w", undecoded[0], ErrInvalidProfile)
+	}
+
+	return profile.complete()
+}
+
+// complete fills the omitted fields of a loaded profile with their defaults and validates it.
+func (p Profile) complete() (Profile, error) {
+	if p.Base == "" {
+		p.Base = StandardProfileName
+	}
+
+	standard := StandardMemoryMap()
+	if p.MemoryMap.ROMSize == 0 {
+		p.MemoryMap.ROMSize = standard.ROMSize
+	}
+	if p.MemoryMap.RAMSize == 0 {
+		p.MemoryMap.RAMSize = standard.RAMSize
+	}
+	if p.MemoryMap.ScreenAddress == 0 {
+		p.MemoryMap.ScreenAddress = standard.ScreenAddress
+	}
+	if p.MemoryMap.KBDAddress == 0 {
+		p.MemoryMap.KBDAddress = standard.KBDAddress
+	}
+
+	err := p.Validate()
+	if err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// Validate returns an error if the memory map is invalid, the base profile is unknown
+// or a symbol can not be loaded by an A-instruction.
+func (p Profile) Validate() error {
+	err := p.MemoryMap.Validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = p.PredefinedSymbols()
+	return err
+}
+
+// PredefinedSymbols returns the symbols predefined by the profile.
+func (p Profile) PredefinedSymbols() (map[string]uint, error) {
+	symbols := make(map[string]uint)
+
+	switch p.Base {
+	case StandardProfileName:
+		symbols["SP"] = spAddress
+		symbols["LCL"] = lclAddress
+		symbols["ARG"] = argAddress
+		symbols["THIS"] = thisAddress
+		symbols["THAT"] = thatAddress
+		symbols["SCREEN"] = p.MemoryMap.ScreenAddress
+		symbols["KBD"] = p.MemoryMap.KBDAddress
+
+		var i uint
+		for i = 0; i < ramAddressNumbers; i++ {
+			symbols[fmt.Sprintf("R%d", i)] = i
+		}
+	case BareProfileName:
+	default:
+		return nil, fmt.Errorf("unknown base %s: %w", p.Base, ErrInvalidProfile)
+	}
+
+	for symbol, address := range p.Symbols {
+		if address > maxAddress {
+			return nil, fmt.Errorf("%s=%d does not fit into 15 bits: %w", symbol, address, ErrInvalidProfile)
+		}
+		_, err := newEntry(symbol, address)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w: %w", symbol, err, ErrInvalidProfile)
+		}
+		symbols[symbol] = address
+	}
+
+	return symbols, nil
+}
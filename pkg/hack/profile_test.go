@@ -0,0 +1,159 @@
+package hack
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLoadProfile(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		json     string
+		symbols  map[string]uint
+		err      error
+	}{
+		{
+			testCase: "extends standard",
+			json:     `{"name": "io-board", "memoryMap": {"screen": 8192, "kbd": 16384}, "symbols": {"LED": 16385}}`,
+			symbols: map[string]uint{
+				"SP": 0, "LCL": 1, "ARG": 2, "THIS": 3, "THAT": 4, "SCREEN": 8192, "KBD": 16384, "LED": 16385,
+				"R0": 0, "R1": 1, "R2": 2, "R3": 3, "R4": 4, "R5": 5, "R6": 6, "R7": 7,
+				"R8": 8, "R9": 9, "R10": 10, "R11": 11, "R12": 12, "R13": 13, "R14": 14, "R15": 15,
+			},
+		},
+		{
+			testCase: "bare",
+			json:     `{"base": "bare", "symbols": {"OUT": 100}}`,
+			symbols:  map[string]uint{"OUT": 100},
+		},
+		{
+			testCase: "invalid symbol",
+			json:     `{"symbols": {"1LED": 16385}}`,
+			err:      ErrInvalidProfile,
+		},
+		{
+			testCase: "unknown base",
+			json:     `{"base": "custom"}`,
+			err:      ErrInvalidProfile,
+		},
+		{
+			testCase: "unknown field",
+			json:     `{"symbol": {"LED": 16385}}`,
+			err:      ErrInvalidProfile,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			profile, err := LoadProfile(strings.NewReader(d.json))
+			if d.err != nil {
+				if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+					t.Fatal(diff)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			symbols, err := profile.PredefinedSymbols()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(symbols, d.symbols); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestLoadProfileTOML(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		toml     string
+		profile  Profile
+		err      error
+	}{
+		{
+			testCase: "extends standard",
+			toml: "name = \"io-board\"\n[memoryMap]\nscreen = 8192\nkbd = 16384\n" +
+				"[symbols]\nLED = 16385\n",
+			profile: Profile{
+				Name:      "io-board",
+				Base:      StandardProfileName,
+				MemoryMap: MemoryMap{ROMSize: 32768, RAMSize: 24577, ScreenAddress: 8192, KBDAddress: 16384},
+				Symbols:   map[string]uint{"LED": 16385},
+			},
+		},
+		{
+			testCase: "bare",
+			toml:     "base = \"bare\"\n",
+			profile:  Profile{Base: BareProfileName, MemoryMap: StandardMemoryMap()},
+		},
+		{
+			testCase: "negative address",
+			toml:     "[symbols]\nLED = -1\n",
+			err:      ErrInvalidProfile,
+		},
+		{
+			testCase: "unknown key",
+			toml:     "[symbol]\nLED = 16385\n",
+			err:      ErrInvalidProfile,
+		},
+		{
+			testCase: "invalid syntax",
+			toml:     "name = io-board\n",
+			err:      ErrInvalidProfile,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			profile, err := LoadProfileTOML(strings.NewReader(d.toml))
+			if diff := cmp.Diff(err, d.err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatal(diff)
+			}
+			if d.err != nil {
+				return
+			}
+
+			if diff := cmp.Diff(profile, d.profile); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestNewAssemblerWithProfile_Bare(t *testing.T) {
+	t.Parallel()
+
+	writer := &bytes.Buffer{}
+
+	assembler, err := NewAssemblerWithProfile(strings.NewReader("@R0\n@SCREEN\n"), writer, BareProfile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(writer.String(), "0000000000010000\n0000000000010001\n"); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,168 @@
+package hack
+
+import (
+	"context"
+	"io"
+	"strconv"
+)
+
+// Position is the location of a statement in the source.
+// Line and Column are 1-based.
+type Position struct {
+	Line   uint
+	Column uint
+}
+
+// Pos returns the position.
+func (p Position) Pos() Position {
+	return p
+}
+
+// Node is a statement of a Program.
+type Node interface {
+	Pos() Position
+	String() string
+}
+
+// AInstruction is an A-instruction.
+// It loads the address of Symbol, or Value if Symbol is empty.
+type AInstruction struct {
+	Position
+	Value  uint
+	Symbol string
+}
+
+func (a *AInstruction) String() string {
+	if a.Symbol != "" {
+		return "@" + a.Symbol
+	}
+	return "@" + strconv.FormatUint(uint64(a.Value), 10)
+}
+
+// CInstruction is a C-instruction.
+// Dest and Jump are empty if they are omitted.
+type CInstruction struct {
+	Position
+	Dest string
+	Comp string
+	Jump string
+}
+
+func (c *CInstruction) String() string {
+	command := c.Comp
+	if c.Dest != "" {
+		command = c.Dest + "=" + command
+	}
+	if c.Jump != "" {
+		command = command + ";" + c.Jump
+	}
+	return command
+}
+
+// Label is a label definition, binding Name to the address of the next instruction.
+type Label struct {
+	Position
+	Name string
+}
+
+func (l *Label) String() string {
+	return "(" + l.Name + ")"
+}
+
+// Directive is an instruction to the assembler, such as .export LOOP.
+// Name is the name of the directive without DirectivePrefix.
+type Directive struct {
+	Position
+	Name     string
+	Argument string
+}
+
+func (d *Directive) String() string {
+	return DirectivePrefix + d.Name + " " + d.Argument
+}
+
+// Comment is a comment, without the leading // and surrounding whitespace.
+type Comment struct {
+	Position
+	Text string
+}
+
+func (c *Comment) String() string {
+	return "// " + c.Text
+}
+
+// Program is a parsed Hack assembly program.
+type Program struct {
+	Nodes []Node
+}
+
+// Parse parses the Hack assembly code read from r into a Program.
+// Comments are kept as Comment nodes.
+func Parse(r io.Reader) (*Program, error) {
+	p := NewParser(r)
+	p.keepComments = true
+
+	return parse(context.Background(), p, 1)
+}
+
+// cancelCheckInterval is the number of statements between checks of the cancellation of an assembly.
+const cancelCheckInterval = 1024
+
+// parse parses the commands read by the parser into a Program.
+// It stops after maxErrors errors, or at the end of the input if maxErrors is less than 1.
+// Read errors of the parser and the cancellation of ctx stop it too.
+func parse(ctx context.Context, p *Parser, maxErrors int) (*Program, error) {
+	program, _, err := parsePartial(ctx, p, maxErrors)
+	if err != nil {
+		return nil, err
+	}
+
+	return program, nil
+}
+
+// parsePartial is like parse, but on syntax errors it also returns the nodes parsed without error,
+// and the number of nodes before the first error.
+// The program is nil if ctx is done or the input could not be read.
+func parsePartial(ctx context.Context, p *Parser, maxErrors int) (*Program, int, error) {
+	program := &Program{}
+	errs := errorList{max: maxErrors}
+	partial := -1
+
+	for statements := 1; p.Advance(); statements++ {
+		if statements%cancelCheckInterval == 0 && ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+
+		node, err := p.Node()
+		if err != nil {
+			if partial < 0 {
+				partial = len(program.Nodes)
+			}
+			if errs.add(err) {
+				break
+			}
+			continue
+		}
+		program.Nodes = append(program.Nodes, node)
+	}
+	if err := p.Err(); err != nil {
+		errs.add(err)
+		program = nil
+	}
+
+	if len(errs.errs) > 0 {
+		return program, partial, errs.err()
+	}
+
+	return program, len(program.Nodes), nil
+}
+
+// Assemble translates the program into Hack machine code for the standard Hack computer.
+func (p *Program) Assemble() ([]uint16, error) {
+	config, err := standardConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return config.NewAssembler(nil, io.Discard).assembleProgram(p)
+}
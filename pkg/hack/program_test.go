@@ -0,0 +1,106 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	program, err := Parse(strings.NewReader("// add\n(LOOP) @i // counter\n  @7;;M=D+1;JMP\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Node{
+		&Comment{Position: Position{1, 1}, Text: "add"},
+		&Label{Position: Position{2, 1}, Name: "LOOP"},
+		&AInstruction{Position: Position{2, 8}, Symbol: "i"},
+		&Comment{Position: Position{2, 11}, Text: "counter"},
+		&AInstruction{Position: Position{3, 3}, Value: 7},
+		&CInstruction{Position: Position{3, 7}, Dest: "M", Comp: "D+1", Jump: "JMP"},
+	}
+
+	if diff := cmp.Diff(program.Nodes, want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+		err      error
+		message  string
+	}{
+		{
+			testCase: "invalid symbol",
+			asm:      "@1abc\n",
+			err:      ErrInvalidSymbol,
+			message:  "line 1, column 1: @1abc: invalid symbol",
+		},
+		{
+			testCase: "invalid comp",
+			asm:      "D=D*A\n",
+			err:      ErrInvalidCompCommand,
+			message:  "line 1, column 1: D*A: invalid comp",
+		},
+		{
+			testCase: "invalid dest",
+			asm:      "X=D\n",
+			err:      ErrInvalidDestCommand,
+			message:  "line 1, column 1: X: invalid dest",
+		},
+		{
+			testCase: "invalid jump",
+			asm:      "0;JUMP\n",
+			err:      ErrInvalidJumpCommand,
+			message:  "line 1, column 1: JUMP: invalid jump",
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(strings.NewReader(d.asm))
+			if !errors.Is(err, d.err) {
+				t.Errorf("expected %v, got %v", d.err, err)
+			}
+			if err != nil && err.Error() != d.message {
+				t.Errorf("expected message %q, got %q", d.message, err.Error())
+			}
+		})
+	}
+}
+
+func TestProgram_Assemble(t *testing.T) {
+	t.Parallel()
+
+	program, err := Parse(strings.NewReader(rectCommands))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := program.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	binary := &strings.Builder{}
+	for _, word := range words {
+		fmt.Fprintf(binary, "%016b\n", word)
+	}
+
+	if diff := cmp.Diff(binary.String(), rectCommandsBinary); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,199 @@
+package hack
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+var (
+	utf8BOM    = []byte{0xef, 0xbb, 0xbf}
+	utf16LEBOM = []byte{0xff, 0xfe}
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// sourceReader reads a source as UTF-8 without a byte order mark.
+// The encoding is detected on the first read:
+// UTF-8, with or without a byte order mark, and UTF-16LE or UTF-16BE,
+// detected by their byte order mark or by the zero high bytes of ASCII text.
+type sourceReader struct {
+	r       io.Reader
+	decoded io.Reader
+}
+
+func newSourceReader(r io.Reader) *sourceReader {
+	return &sourceReader{r: r}
+}
+
+func (s *sourceReader) Read(b []byte) (int, error) {
+	if s.decoded == nil {
+		s.decoded = detectEncoding(bufio.NewReader(s.r))
+	}
+
+	return s.decoded.Read(b)
+}
+
+// detectEncoding returns a reader decoding br into UTF-8, skipping its byte order mark.
+func detectEncoding(br *bufio.Reader) io.Reader {
+	// A read error is returned again by the next read of br.
+	head, _ := br.Peek(len(utf8BOM))
+
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		_, _ = br.Discard(len(utf8BOM))
+		return br
+	case bytes.HasPrefix(head, utf16LEBOM):
+		_, _ = br.Discard(len(utf16LEBOM))
+		return &utf16Reader{r: br, order: binary.LittleEndian}
+	case bytes.HasPrefix(head, utf16BEBOM):
+		_, _ = br.Discard(len(utf16BEBOM))
+		return &utf16Reader{r: br, order: binary.BigEndian}
+	case len(head) >= 2 && head[0] != 0 && head[1] == 0:
+		return &utf16Reader{r: br, order: binary.LittleEndian}
+	case len(head) >= 2 && head[0] == 0 && head[1] != 0:
+		return &utf16Reader{r: br, order: binary.BigEndian}
+	}
+
+	return br
+}
+
+// ErrInvalidEncoding is returned when a UTF-16 source ends in the middle of a code unit.
+var ErrInvalidEncoding = errors.New("invalid encoding")
+
+// utf16Reader decodes UTF-16 into UTF-8.
+// Unpaired surrogates are decoded as U+FFFD.
+type utf16Reader struct {
+	r     io.Reader
+	order binary.ByteOrder
+
+	// pending is a code unit read after an unpaired high surrogate.
+	pending    uint16
+	hasPending bool
+
+	// encoded holds the UTF-8 bytes not returned yet.
+	encoded []byte
+
+	// err is the error returned once the encoded bytes are returned.
+	err error
+}
+
+func (u *utf16Reader) Read(b []byte) (int, error) {
+	n := copy(b, u.encoded)
+	u.encoded = u.encoded[n:]
+
+	for n < len(b) && u.err == nil {
+		r, err := u.readRune()
+		if err != nil {
+			u.err = err
+			break
+		}
+
+		var buf [utf8.UTFMax]byte
+		size := utf8.EncodeRune(buf[:], r)
+		copied := copy(b[n:], buf[:size])
+		n += copied
+		u.encoded = append(u.encoded, buf[copied:size]...)
+	}
+
+	if n == 0 && len(u.encoded) == 0 {
+		return 0, u.err
+	}
+
+	return n, nil
+}
+
+func (u *utf16Reader) readRune() (rune, error) {
+	unit, err := u.readUnit()
+	if err != nil {
+		return 0, err
+	}
+
+	r := rune(unit)
+	if !utf16.IsSurrogate(r) {
+		return r, nil
+	}
+
+	next, err := u.readUnit()
+	if errors.Is(err, io.EOF) {
+		return utf8.RuneError, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	decoded := utf16.DecodeRune(r, rune(next))
+	if decoded == utf8.RuneError {
+		u.pending = next
+		u.hasPending = true
+	}
+
+	return decoded, nil
+}
+
+func (u *utf16Reader) readUnit() (uint16, error) {
+	if u.hasPending {
+		u.hasPending = false
+		return u.pending, nil
+	}
+
+	var buf [2]byte
+	_, err := io.ReadFull(u.r, buf[:])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, fmt.Errorf("UTF-16 source ends with an odd byte: %w", ErrInvalidEncoding)
+	} else if err != nil {
+		return 0, err
+	}
+
+	return u.order.Uint16(buf[:]), nil
+}
+
+// scanLines is a bufio.SplitFunc splitting lines ended by "\n", "\r\n" or a lone "\r".
+// Each line break is a single "\r", "\n" or "\r\n", so that blank lines are counted with any line ending.
+func scanLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+
+	i := bytes.IndexAny(data, "\r\n")
+	if i < 0 {
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+
+	end := i + 1
+	if data[i] == '\r' {
+		if end == len(data) && !atEOF {
+			// The carriage return may be followed by a line feed.
+			return 0, nil, nil
+		}
+		if end < len(data) && data[end] == '\n' {
+			end++
+		}
+	}
+
+	return end, data[:i], nil
+}
+
+// DefaultTabWidth is the default distance between tab stops used to compute columns.
+const DefaultTabWidth = 8
+
+// columnOf returns the 1-based column of the byte offset in the line,
+// counting characters and expanding tabs to the next multiple of tabWidth.
+func columnOf(line string, offset int, tabWidth int) uint {
+	column := 0
+	for _, r := range line[:offset] {
+		if r == '\t' && tabWidth > 0 {
+			column = (column/tabWidth + 1) * tabWidth
+			continue
+		}
+		column++
+	}
+
+	return uint(column + 1)
+}
@@ -0,0 +1,165 @@
+package hack
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// encodeUTF16 encodes s in UTF-16 with the byte order, prefixed with its byte order mark if bom is true.
+func encodeUTF16(s string, order binary.ByteOrder, bom bool) string {
+	units := utf16.Encode([]rune(s))
+	if bom {
+		units = append([]uint16{0xfeff}, units...)
+	}
+
+	b := make([]byte, 2*len(units))
+	for i, unit := range units {
+		order.PutUint16(b[2*i:], unit)
+	}
+
+	return string(b)
+}
+
+func TestAssembler_Assemble_Encodings(t *testing.T) {
+	t.Parallel()
+
+	// The comment holds a character outside of the Basic Multilingual Plane.
+	source := "// max 🎉\n" + maxCommands
+	crlf := strings.ReplaceAll(source, "\n", "\r\n")
+
+	data := []struct {
+		testCase string
+		asm      string
+	}{
+		{"UTF-8 BOM", "\xef\xbb\xbf" + source},
+		{"UTF-16LE BOM", encodeUTF16(source, binary.LittleEndian, true)},
+		{"UTF-16BE BOM", encodeUTF16(source, binary.BigEndian, true)},
+		{"UTF-16LE", encodeUTF16(source, binary.LittleEndian, false)},
+		{"UTF-16BE", encodeUTF16(source, binary.BigEndian, false)},
+		{"UTF-16LE BOM CRLF", encodeUTF16(crlf, binary.LittleEndian, true)},
+		{"CRLF", crlf},
+		{"CR", strings.ReplaceAll(source, "\n", "\r")},
+		{"CR CRLF", strings.ReplaceAll(source, "\n", "\r\r\n")},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &bytes.Buffer{}
+			assembler, err := NewAssembler(strings.NewReader(d.asm), writer)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(writer.String(), maxCommandsBinary); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestParse_LineEndings_BlankLines(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+	}{
+		{"LF", "@1\n\n\nD=X\n"},
+		{"CRLF", "@1\r\n\r\n\r\nD=X\r\n"},
+		{"CR", "@1\r\r\rD=X\r"},
+		{"mixed", "@1\r\n\r\rD=X"},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse(strings.NewReader(d.asm))
+			var sourceErr *SourceError
+			if !errors.As(err, &sourceErr) || sourceErr.Line != 4 {
+				t.Errorf("expected an error at line 4, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_OddUTF16(t *testing.T) {
+	t.Parallel()
+
+	asm := encodeUTF16("@1\n", binary.LittleEndian, true) + "\x00"
+
+	assembler, err := NewAssembler(strings.NewReader(asm), &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Errorf("expected ErrInvalidEncoding, got %v", err)
+	}
+}
+
+func TestParser_Column_Multibyte(t *testing.T) {
+	t.Parallel()
+
+	p := NewParser(strings.NewReader("É;;D=X\n"))
+	p.Advance()
+	p.Advance()
+
+	if p.Column() != 4 {
+		t.Errorf("expected column 4, got %d", p.Column())
+	}
+}
+
+func TestSourceError_TabColumns(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		asm      string
+		tabWidth int
+		column   uint
+	}{
+		{"spaces", "    D=X\n", DefaultTabWidth, 5},
+		{"tab", "\tD=X\n", DefaultTabWidth, 9},
+		{"tab width 4", "\tD=X\n", 4, 5},
+		{"tab after spaces", "  \tD=X\n", 4, 5},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			assembler, err := NewAssembler(strings.NewReader(d.asm), &bytes.Buffer{}, WithTabWidth(d.tabWidth))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+
+			var sourceErr *SourceError
+			if !errors.As(err, &sourceErr) {
+				t.Fatalf("expected SourceError, got %v", err)
+			}
+			if sourceErr.Column != d.column {
+				t.Errorf("expected column %d, got %d", d.column, sourceErr.Column)
+			}
+		})
+	}
+}
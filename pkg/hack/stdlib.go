@@ -0,0 +1,200 @@
+package hack
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// UseDirective includes a routine of the standard library in the program, as in .use mult.
+const UseDirective = "use"
+
+// stdlib holds the sources of the standard library.
+//
+//go:embed stdlib/*.asm
+var stdlib embed.FS
+
+// libraryFiles maps the routines of the standard library to the files defining them.
+// A file may define several routines sharing their code.
+var libraryFiles = map[string]string{
+	"mult":       "mult.asm",
+	"div":        "div.asm",
+	"mod":        "div.asm",
+	"memcpy":     "memcpy.asm",
+	"memset":     "memset.asm",
+	"fillscreen": "fillscreen.asm",
+	"drawpixel":  "drawpixel.asm",
+	"readkey":    "readkey.asm",
+}
+
+// Errors of the standard library.
+var (
+	// ErrUnknownRoutine is returned when a .use directive names no routine of the standard library.
+	ErrUnknownRoutine = errors.New("unknown library routine")
+	// ErrLibrarySymbol is returned when a routine uses a symbol that the configuration does not predefine.
+	ErrLibrarySymbol = errors.New("symbol used by the library not predefined")
+)
+
+// LibraryRoutines returns the names of the routines of the standard library, sorted.
+func LibraryRoutines() []string {
+	names := make([]string, 0, len(libraryFiles))
+	for name := range libraryFiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// LibrarySource returns the assembly code of the file defining the routine of the standard library.
+func LibrarySource(routine string) ([]byte, error) {
+	file, ok := libraryFiles[routine]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", routine, ErrUnknownRoutine)
+	}
+
+	return stdlib.ReadFile("stdlib/" + file)
+}
+
+// includeLibrary appends the files of the routines used by the program with .use directives,
+// each once, in the order of their first use. The program is not modified.
+func (a *Assembler) includeLibrary(program *Program, errs *errorList) *Program {
+	included := make(map[string]bool)
+	var nodes []Node
+
+	for _, node := range program.Nodes {
+		directive, ok := node.(*Directive)
+		if !ok || directive.Name != UseDirective {
+			continue
+		}
+
+		file, ok := libraryFiles[directive.Argument]
+		if !ok {
+			if errs.add(newSourceError(node, "%s: %w", node, ErrUnknownRoutine)) {
+				return program
+			}
+			continue
+		}
+		if included[file] {
+			continue
+		}
+		included[file] = true
+
+		library, err := a.parseLibrary(file)
+		if err != nil {
+			if errs.add(newSourceError(node, "%s: %w", node, err)) {
+				return program
+			}
+			continue
+		}
+		nodes = append(nodes, library.Nodes...)
+	}
+
+	if len(nodes) == 0 {
+		return program
+	}
+
+	return &Program{Nodes: append(program.Nodes[:len(program.Nodes):len(program.Nodes)], nodes...)}
+}
+
+// usedRoutines returns the routines used by the program with .use directives, each once,
+// in the order of their first use, and adds the unknown routines to errs.
+func usedRoutines(program *Program, errs *errorList) []string {
+	used := make(map[string]bool)
+	var routines []string
+
+	for _, node := range program.Nodes {
+		directive, ok := node.(*Directive)
+		if !ok || directive.Name != UseDirective || used[directive.Argument] {
+			continue
+		}
+
+		if _, ok := libraryFiles[directive.Argument]; !ok {
+			if errs.add(newSourceError(node, "%s: %w", node, ErrUnknownRoutine)) {
+				return routines
+			}
+			continue
+		}
+		used[directive.Argument] = true
+		routines = append(routines, directive.Argument)
+	}
+
+	return routines
+}
+
+// includeLibrary appends the objects of the files of the routines used by the objects,
+// each once, in the order of their first use. The objects are not modified.
+// The unknown routines are skipped, since the objects using them are invalid.
+func (l *Linker) includeLibrary(objects []*Object) ([]*Object, error) {
+	included := make(map[string]bool)
+	var library []*Object
+
+	for _, object := range objects {
+		for _, routine := range object.Uses {
+			file, ok := libraryFiles[routine]
+			if !ok || included[file] {
+				continue
+			}
+			included[file] = true
+
+			a := l.config.NewAssembler(nil, io.Discard)
+			program, err := a.parseLibrary(file)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", routine, err)
+			}
+			o, err := a.assembleObject(program)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", routine, err)
+			}
+			o.Name = "stdlib/" + file
+			library = append(library, o)
+		}
+	}
+
+	if len(library) == 0 {
+		return objects, nil
+	}
+
+	return append(objects[:len(objects):len(objects)], library...), nil
+}
+
+// isLibrarySymbol returns true for the predefined symbols used by the routines of the standard library.
+func isLibrarySymbol(symbol string) bool {
+	switch symbol {
+	case "R13", "R14", "R15", "SCREEN", "KBD":
+		return true
+	}
+
+	return false
+}
+
+// parseLibrary parses a file of the standard library with the instruction set of the Assembler.
+// It returns ErrLibrarySymbol if the configuration does not predefine the symbols used by the file,
+// which would otherwise be allocated as private variables of the file.
+func (a *Assembler) parseLibrary(file string) (*Program, error) {
+	f, err := stdlib.Open("stdlib/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	p := NewParser(f)
+	p.SetInstructionSet(a.code)
+
+	program, err := parse(context.Background(), p, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range program.Nodes {
+		command, ok := node.(*AInstruction)
+		if ok && isLibrarySymbol(command.Symbol) && !a.config.predefined.Contains(command.Symbol) {
+			return nil, fmt.Errorf("%s: %w", command.Symbol, ErrLibrarySymbol)
+		}
+	}
+
+	return program, nil
+}
@@ -0,0 +1,84 @@
+// std.div: D = R13 / R14, and R13 = R13 % R14.
+// std.mod: D = R13 % R14, and R13 = R13 % R14.
+// R13 must not be negative, and R14 must be positive.
+// Long division of R13, one bit per iteration from its most significant bit.
+.file std_div
+(std.mod)
+@ret
+M=D
+@remainder
+M=-1
+@START
+0;JMP
+(std.div)
+@ret
+M=D
+@remainder
+M=0
+(START)
+@quotient
+M=0
+@rest
+M=0
+@16
+D=A
+@count
+M=D
+(LOOP)
+// rest = 2 * rest + the most significant bit of R13, and R13 = 2 * R13.
+@rest
+D=M
+M=D+M
+@R13
+D=M
+@SHIFT
+D;JGE
+@rest
+M=M+1
+(SHIFT)
+@R13
+D=M
+M=D+M
+@quotient
+D=M
+M=D+M
+// rest >= R14, with rest read as unsigned.
+@rest
+D=M
+@SUBTRACT
+D;JLT
+@R14
+D=D-M
+@NEXT
+D;JLT
+(SUBTRACT)
+@R14
+D=M
+@rest
+M=M-D
+@quotient
+M=M+1
+(NEXT)
+@count
+MD=M-1
+@LOOP
+D;JGT
+@rest
+D=M
+@R13
+M=D
+@remainder
+D=M
+@REMAINDER
+D;JNE
+@quotient
+D=M
+@ret
+A=M
+0;JMP
+(REMAINDER)
+@R13
+D=M
+@ret
+A=M
+0;JMP
@@ -0,0 +1,61 @@
+// std.drawpixel: draws the pixel at the column R13 and the row R14 in black.
+// The pixel is bit R13 % 16 of the word SCREEN + 32 * R14 + R13 / 16,
+// so R13 must be between 0 and 511, and R14 between 0 and 255.
+.file std_drawpixel
+(std.drawpixel)
+@ret
+M=D
+@R14
+D=M
+@address
+M=D
+MD=D+M
+MD=D+M
+MD=D+M
+MD=D+M
+MD=D+M
+@SCREEN
+D=A
+@address
+M=D+M
+@R13
+D=M
+@column
+M=D
+(WORD)
+@column
+D=M
+@16
+D=D-A
+@BIT
+D;JLT
+@column
+M=D
+@address
+M=M+1
+@WORD
+0;JMP
+(BIT)
+@bit
+M=1
+(SHIFT)
+@column
+D=M
+@SET
+D;JLE
+@column
+M=M-1
+@bit
+D=M
+M=D+M
+@SHIFT
+0;JMP
+(SET)
+@bit
+D=M
+@address
+A=M
+M=D|M
+@ret
+A=M
+0;JMP
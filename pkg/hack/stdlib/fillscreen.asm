@@ -0,0 +1,30 @@
+// std.fillscreen: sets every word of the screen, from SCREEN up to KBD, to R13.
+// R13 is 0 to clear the screen, and -1 to fill it in black.
+.file std_fillscreen
+(std.fillscreen)
+@ret
+M=D
+@SCREEN
+D=A
+@address
+M=D
+(LOOP)
+@KBD
+D=A
+@address
+D=D-M
+@END
+D;JLE
+@R13
+D=M
+@address
+A=M
+M=D
+@address
+M=M+1
+@LOOP
+0;JMP
+(END)
+@ret
+A=M
+0;JMP
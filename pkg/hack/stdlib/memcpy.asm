@@ -0,0 +1,30 @@
+// std.memcpy: copies R15 words from the address R13 to the address R14.
+// The words are copied in increasing addresses, so the destination may overlap
+// the source only below it.
+.file std_memcpy
+(std.memcpy)
+@ret
+M=D
+(LOOP)
+@R15
+D=M
+@END
+D;JLE
+@R13
+A=M
+D=M
+@R14
+A=M
+M=D
+@R13
+M=M+1
+@R14
+M=M+1
+@R15
+M=M-1
+@LOOP
+0;JMP
+(END)
+@ret
+A=M
+0;JMP
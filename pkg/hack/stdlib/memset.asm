@@ -0,0 +1,25 @@
+// std.memset: sets R15 words from the address R13 to R14.
+.file std_memset
+(std.memset)
+@ret
+M=D
+(LOOP)
+@R15
+D=M
+@END
+D;JLE
+@R14
+D=M
+@R13
+A=M
+M=D
+@R13
+M=M+1
+@R15
+M=M-1
+@LOOP
+0;JMP
+(END)
+@ret
+A=M
+0;JMP
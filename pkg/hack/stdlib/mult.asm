@@ -0,0 +1,36 @@
+// std.mult: D = R13 * R14, modulo 2^16.
+// Adds R13 shifted left for each bit set in R14, so that it takes 16 iterations
+// whatever the operands, and works for negative operands too.
+.file std_mult
+(std.mult)
+@ret
+M=D
+@result
+M=0
+@bit
+M=1
+(LOOP)
+@R14
+D=M
+@bit
+D=D&M
+@SKIP
+D;JEQ
+@R13
+D=M
+@result
+M=D+M
+(SKIP)
+@R13
+D=M
+M=D+M
+@bit
+D=M
+MD=D+M
+@LOOP
+D;JNE
+@result
+D=M
+@ret
+A=M
+0;JMP
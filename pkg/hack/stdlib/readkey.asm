@@ -0,0 +1,14 @@
+// std.readkey: waits until a key is pressed, and returns its code in D.
+// The key may still be pressed when it returns.
+.file std_readkey
+(std.readkey)
+@ret
+M=D
+(WAIT)
+@KBD
+D=M
+@WAIT
+D;JEQ
+@ret
+A=M
+0;JMP
@@ -0,0 +1,304 @@
+package hack
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// emulator is a standard Hack computer running machine code, to test the standard library.
+type emulator struct {
+	rom  []uint16
+	ram  []uint16
+	a, d uint16
+	pc   uint16
+}
+
+// compute returns the output of the ALU for the comp bits of the instruction.
+func compute(word uint16, x uint16, y uint16) uint16 {
+	comp := word & CompMask >> CompShift
+	if comp&0b100000 != 0 {
+		x = 0
+	}
+	if comp&0b010000 != 0 {
+		x = ^x
+	}
+	if comp&0b001000 != 0 {
+		y = 0
+	}
+	if comp&0b000100 != 0 {
+		y = ^y
+	}
+	out := x & y
+	if comp&0b000010 != 0 {
+		out = x + y
+	}
+	if comp&0b000001 != 0 {
+		out = ^out
+	}
+
+	return out
+}
+
+// step runs the instruction at the program counter.
+func (e *emulator) step() {
+	word := e.rom[e.pc]
+	if word&CInstructionBit == 0 {
+		e.a = word
+		e.pc++
+		return
+	}
+
+	y := e.a
+	if word&ABit != 0 {
+		y = e.ram[e.a]
+	}
+	out := compute(word, e.d, y)
+
+	address := e.a
+	if word&DestM != 0 {
+		e.ram[address] = out
+	}
+	if word&DestA != 0 {
+		e.a = out
+	}
+	if word&DestD != 0 {
+		e.d = out
+	}
+
+	value := int16(out)
+	if value < 0 && word&JumpLT != 0 || value == 0 && word&JumpEQ != 0 || value > 0 && word&JumpGT != 0 {
+		e.pc = address
+		return
+	}
+	e.pc++
+}
+
+// callRoutine assembles a program calling the routine of the standard library,
+// runs it with the registers set to args from R13, and returns the RAM and D after the call.
+func callRoutine(t *testing.T, routine string, ram map[uint16]uint16, args ...uint16) ([]uint16, uint16) {
+	t.Helper()
+
+	source := fmt.Sprintf(".use %s\n@RET\nD=A\n@std.%s\n0;JMP\n(RET)\n(END)\n@END\n0;JMP\n", routine, routine)
+	assembler, err := NewAssembler(strings.NewReader(source), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom, err := assembler.assembleProgram(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, err := assembler.SymbolTable().GetAddress("END")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := &emulator{rom: rom, ram: make([]uint16, StandardMemoryMap().RAMSize)}
+	for address, value := range ram {
+		e.ram[address] = value
+	}
+	for i, arg := range args {
+		e.ram[13+i] = arg
+	}
+
+	for steps := 0; e.pc != uint16(end); steps++ {
+		if steps > 1_000_000 {
+			t.Fatalf("%s did not return", routine)
+		}
+		e.step()
+	}
+
+	return e.ram, e.d
+}
+
+// word returns the two's complement of n.
+func word(n int) uint16 {
+	return uint16(int16(n))
+}
+
+func TestLibrary_Arithmetic(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		routine string
+		x, y    int
+		want    int
+	}{
+		{"mult", 7, 6, 42},
+		{"mult", -3, 5, -15},
+		{"mult", -4, -8, 32},
+		{"mult", 0, 123, 0},
+		{"mult", 300, 300, int(int16(90000 % 65536))},
+		{"div", 42, 5, 8},
+		{"div", 5, 10, 0},
+		{"div", 32767, 1, 32767},
+		{"div", 32767, 16384, 1},
+		{"div", 30000, 30000, 1},
+		{"mod", 42, 5, 2},
+		{"mod", 32767, 16384, 16383},
+		{"mod", 9, 3, 0},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(fmt.Sprintf("%s(%d,%d)", d.routine, d.x, d.y), func(t *testing.T) {
+			t.Parallel()
+
+			_, got := callRoutine(t, d.routine, nil, word(d.x), word(d.y))
+			if got != word(d.want) {
+				t.Errorf("expected %d, got %d", d.want, int16(got))
+			}
+		})
+	}
+}
+
+func TestLibrary_Div_Remainder(t *testing.T) {
+	t.Parallel()
+
+	ram, quotient := callRoutine(t, "div", nil, 100, 7)
+	if quotient != 14 || ram[13] != 2 {
+		t.Errorf("expected 14 remainder 2, got %d remainder %d", quotient, ram[13])
+	}
+}
+
+func TestLibrary_Memory(t *testing.T) {
+	t.Parallel()
+
+	source := map[uint16]uint16{100: 1, 101: 2, 102: 3, 103: 4, 200: 9, 203: 9}
+	ram, _ := callRoutine(t, "memcpy", source, 100, 200, 3)
+	for i, want := range []uint16{1, 2, 3, 9} {
+		if ram[200+i] != want {
+			t.Errorf("memcpy: RAM[%d] = %d, expected %d", 200+i, ram[200+i], want)
+		}
+	}
+
+	ram, _ = callRoutine(t, "memset", map[uint16]uint16{303: 5}, 300, word(-1), 3)
+	for i, want := range []uint16{0xffff, 0xffff, 0xffff, 5} {
+		if ram[300+i] != want {
+			t.Errorf("memset: RAM[%d] = %d, expected %d", 300+i, ram[300+i], want)
+		}
+	}
+}
+
+func TestLibrary_Screen(t *testing.T) {
+	t.Parallel()
+
+	m := StandardMemoryMap()
+	screen := uint16(m.ScreenAddress)
+	kbd := uint16(m.KBDAddress)
+
+	ram, _ := callRoutine(t, "fillscreen", map[uint16]uint16{kbd: 7}, word(-1))
+	if ram[screen] != 0xffff || ram[kbd-1] != 0xffff || ram[kbd] != 7 || ram[screen-1] != 0 {
+		t.Error("fillscreen did not fill exactly the screen")
+	}
+
+	data := []struct {
+		x, y    uint16
+		address uint16
+		bit     uint16
+	}{
+		{0, 0, screen, 1},
+		{17, 2, screen + 65, 2},
+		{511, 255, kbd - 1, 0x8000},
+	}
+	for _, d := range data {
+		ram, _ := callRoutine(t, "drawpixel", map[uint16]uint16{d.address: 0b1000}, d.x, d.y)
+		if ram[d.address] != d.bit|0b1000 {
+			t.Errorf("drawpixel(%d, %d): RAM[%d] = %b, expected %b", d.x, d.y, d.address, ram[d.address], d.bit|0b1000)
+		}
+	}
+}
+
+func TestLibrary_ReadKey(t *testing.T) {
+	t.Parallel()
+
+	_, key := callRoutine(t, "readkey", map[uint16]uint16{uint16(StandardMemoryMap().KBDAddress): 65})
+	if key != 65 {
+		t.Errorf("expected 65, got %d", key)
+	}
+}
+
+func TestAssembler_Assemble_Use(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		size     int
+	}{
+		{"not used", "@1\n", 1},
+		{"used once", ".use mult\n.use mult\n@std.mult\n", 1 + 29},
+		{"shared file", ".use div\n.use mod\n@std.div\n@std.mod\n", 2 + 69},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			assembler, err := NewAssembler(strings.NewReader(d.source), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assembler.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if assembler.Size() != d.size {
+				t.Errorf("expected %d words, got %d", d.size, assembler.Size())
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_UnknownRoutine(t *testing.T) {
+	t.Parallel()
+
+	assembler, err := NewAssembler(strings.NewReader(".use sqrt\n"), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if !errors.Is(err, ErrUnknownRoutine) {
+		t.Errorf("expected ErrUnknownRoutine, got %v", err)
+	}
+}
+
+func TestAssembler_Assemble_UseBareProfile(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		symbols  map[string]uint
+		err      error
+	}{
+		{"not predefined", nil, ErrLibrarySymbol},
+		{"predefined by the profile", map[string]uint{"R13": 13, "R14": 14}, nil},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			profile := BareProfile()
+			profile.Symbols = d.symbols
+			assembler, err := NewAssembler(strings.NewReader(".use mult\n@std.mult\n"), io.Discard, WithProfile(profile))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = assembler.Assemble()
+			if !errors.Is(err, d.err) {
+				t.Errorf("expected %v, got %v", d.err, err)
+			}
+		})
+	}
+}
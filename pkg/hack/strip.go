@@ -0,0 +1,139 @@
+package hack
+
+// routine is a run of statements starting at a label, or at the entry point of the program,
+// up to the next label.
+type routine struct {
+	// start and end are the indexes of the nodes of the routine in the program.
+	start, end int
+
+	// references are the symbols used by the A-instructions of the routine, as named in the symbol table.
+	references []string
+
+	// fallsThrough is true if the last instruction of the routine can continue into the next routine.
+	fallsThrough bool
+}
+
+// isUnconditionalJump returns true if the instruction always jumps.
+func isUnconditionalJump(command *CInstruction) bool {
+	switch command.Jump {
+	case "JMP":
+		return true
+	case "JEQ", "JGE", "JLE":
+		return command.Comp == "0"
+	}
+
+	return false
+}
+
+// splitRoutines splits the program into routines, starting a routine at each label.
+// It returns the routines and the index of the routine of each label, named as in the symbol table.
+func (a *Assembler) splitRoutines(program *Program, ns *namespaces) ([]routine, map[string]int) {
+	routines := []routine{{fallsThrough: true}}
+	labels := make(map[string]int)
+
+	file := ""
+	for i, node := range program.Nodes {
+		file = nextFile(node, file)
+		current := &routines[len(routines)-1]
+
+		switch command := node.(type) {
+		case *Label:
+			current.end = i
+			labels[ns.label(file, command.Name)] = len(routines)
+			routines = append(routines, routine{start: i, fallsThrough: true})
+		case *AInstruction:
+			if command.Symbol != "" {
+				current.references = append(current.references, ns.reference(file, command.Symbol, a.config.predefined))
+			}
+			current.fallsThrough = true
+		case *CInstruction:
+			current.fallsThrough = !isUnconditionalJump(command)
+		}
+	}
+	routines[len(routines)-1].end = len(program.Nodes)
+
+	return routines, labels
+}
+
+// strip removes the routines that can not be reached from the entry point of the program,
+// and returns the program left.
+// A routine is reached if the routine before it falls through into it,
+// or if an A-instruction of a reached routine refers to its label.
+// Any reference counts, not only jumps, since an address loaded into D may be jumped to later,
+// as the return address of a call is.
+// The routines of labels kept with the .keep directive are reached too.
+// It sets the number of words removed, and adds the errors of the directives to errs.
+func (a *Assembler) strip(program *Program, errs *errorList) *Program {
+	ns := newNamespaces(program, errs)
+	if len(errs.errs) > 0 {
+		return program
+	}
+
+	routines, labels := a.splitRoutines(program, ns)
+	reached := markReached(routines, labels, ns.kept)
+
+	return a.removeUnreached(program, ns, routines, labels, reached)
+}
+
+// markReached returns whether each routine is reached from the entry point of the program
+// or from the kept labels.
+func markReached(routines []routine, labels map[string]int, kept map[string]bool) []bool {
+	reached := make([]bool, len(routines))
+	queue := []int{0}
+	for label := range kept {
+		queue = append(queue, labels[label])
+	}
+	for len(queue) > 0 {
+		i := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+		if reached[i] {
+			continue
+		}
+		reached[i] = true
+
+		if routines[i].fallsThrough && i+1 < len(routines) {
+			queue = append(queue, i+1)
+		}
+		for _, symbol := range routines[i].references {
+			if j, ok := labels[symbol]; ok {
+				queue = append(queue, j)
+			}
+		}
+	}
+
+	return reached
+}
+
+// removeUnreached returns the program without the routines not reached,
+// and counts the words removed.
+func (a *Assembler) removeUnreached(program *Program, ns *namespaces,
+	routines []routine, labels map[string]int, reached []bool,
+) *Program {
+	stripped := &Program{Nodes: make([]Node, 0, len(program.Nodes))}
+	file := ""
+	for i, r := range routines {
+		for _, node := range program.Nodes[r.start:r.end] {
+			file = nextFile(node, file)
+
+			switch command := node.(type) {
+			case *AInstruction, *CInstruction:
+				if !reached[i] {
+					a.stripped++
+					continue
+				}
+			case *Label:
+				if !reached[i] {
+					continue
+				}
+			case *Directive:
+				// The exports of the labels removed are removed too.
+				if command.Name == ExportDirective && !reached[labels[ns.label(file, command.Argument)]] {
+					continue
+				}
+			}
+			stripped.Nodes = append(stripped.Nodes, node)
+		}
+	}
+
+	return stripped
+}
@@ -0,0 +1,157 @@
+package hack
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	// stripMain calls DOUBLE, and ends in an infinite loop.
+	stripMain = `@RET
+D=A
+@ret
+M=D
+@DOUBLE
+0;JMP
+(RET)
+@END
+(END)
+0;JMP
+`
+	stripDouble = `(DOUBLE)
+@x
+M=M+1
+@ret
+A=M
+0;JMP
+`
+	stripHalf = `(HALF)
+@x
+M=M-1
+(HALF_LOOP)
+@HALF_LOOP
+D;JGT
+@ret
+A=M
+0;JMP
+`
+	stripInterrupt = `(ISR)
+@y
+M=0
+@ISR
+0;JMP
+`
+)
+
+func TestAssembler_Assemble_Strip(t *testing.T) {
+	t.Parallel()
+
+	data := []struct {
+		testCase string
+		source   string
+		want     string
+		stripped int
+	}{
+		{
+			testCase: "unreferenced routines",
+			source:   stripMain + stripDouble + stripHalf + stripInterrupt,
+			want:     stripMain + stripDouble,
+			stripped: 11,
+		},
+		{
+			testCase: "kept routine",
+			source:   ".keep ISR\n" + stripMain + stripDouble + stripHalf + stripInterrupt,
+			want:     stripMain + stripDouble + stripInterrupt,
+			stripped: 7,
+		},
+		{
+			testCase: "fall through",
+			source:   "@1\nD=A\n" + stripDouble,
+			want:     "@1\nD=A\n" + stripDouble,
+		},
+		{
+			testCase: "exported routine",
+			source:   stripMain + stripDouble + ".file Math\n.export HALF\n" + stripHalf,
+			want:     stripMain + stripDouble,
+			stripped: 7,
+		},
+		{
+			testCase: "unconditional jump with a constant comp",
+			source:   "@END\n(END)\n0;JEQ\n" + stripHalf,
+			want:     "@END\n(END)\n0;JEQ\n",
+			stripped: 7,
+		},
+	}
+
+	for _, d := range data {
+		d := d
+		t.Run(d.testCase, func(t *testing.T) {
+			t.Parallel()
+
+			writer := &bytes.Buffer{}
+			assembler, err := NewAssembler(strings.NewReader(d.source), writer, WithStrip(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = assembler.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := &bytes.Buffer{}
+			expected, err := NewAssembler(strings.NewReader(d.want), want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = expected.Assemble()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(writer.String(), want.String()); diff != "" {
+				t.Error(diff)
+			}
+			if assembler.Stripped() != d.stripped {
+				t.Errorf("expected %d words stripped, got %d", d.stripped, assembler.Stripped())
+			}
+		})
+	}
+}
+
+func TestAssembler_Assemble_NoStrip(t *testing.T) {
+	t.Parallel()
+
+	source := stripMain + stripDouble + stripHalf
+
+	assembler, err := NewAssembler(strings.NewReader(source), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = assembler.Assemble()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if assembler.Stripped() != 0 || assembler.Size() != 20 {
+		t.Errorf("expected 20 words and none stripped, got %d and %d stripped", assembler.Size(), assembler.Stripped())
+	}
+}
+
+func TestAssembler_Assemble_UndefinedKeep(t *testing.T) {
+	t.Parallel()
+
+	assembler, err := NewAssembler(strings.NewReader(".keep ISR\n@1\n"), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = assembler.Assemble()
+	if !errors.Is(err, ErrSymbolNotFound) {
+		t.Errorf("expected ErrSymbolNotFound, got %v", err)
+	}
+}
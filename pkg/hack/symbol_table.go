@@ -3,7 +3,7 @@ package hack
 import (
 	"errors"
 	"fmt"
-	"regexp"
+	"sort"
 )
 
 // Entry represents a symbol table entry.
@@ -12,21 +12,29 @@ type Entry struct {
 	address uint
 }
 
-const (
-	screenAddress uint = 16384
-)
-
 // ErrInvalidSymbol is returned when the symbol is invalid.
 var ErrInvalidSymbol = errors.New("invalid symbol")
 
+// isSymbol returns true if the string is a valid symbol:
+// a letter, '_', '.', '$' or ':' followed by any of those or digits.
+func isSymbol(symbol string) bool {
+	if symbol == "" || isDigit(symbol[0]) {
+		return false
+	}
+
+	for i := 0; i < len(symbol); i++ {
+		if !isSymbolPart(symbol[i]) || symbol[i] == '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
 func newEntry(symbol string, address uint) (Entry, error) {
 	var entry Entry
-	ok, err := regexp.MatchString("^[a-zA-Z_.$:][a-zA-Z0-9_.$:]*$", symbol)
-	if err != nil {
-		return entry, err
-	}
 
-	if !ok {
+	if !isSymbol(symbol) {
 		return entry, fmt.Errorf("could not create entry: %w", ErrInvalidSymbol)
 	}
 
@@ -39,8 +47,10 @@ func newEntry(symbol string, address uint) (Entry, error) {
 // SymbolTable is a data structure that is used to map symbolic labels or
 // variables to their corresponding numeric addresses. It serves as a lookup table for
 // retrieving addresses based on symbol names.
+// A symbol table may be scoped on top of a parent table, which it reads but never modifies.
 type SymbolTable struct {
-	entries []Entry
+	parent    *SymbolTable
+	addresses map[string]uint
 }
 
 const (
@@ -50,7 +60,15 @@ const (
 // NewSymbolTable creates a new symbol table.
 // It initializes the table with the predefined symbols.
 func NewSymbolTable() *SymbolTable {
-	table := &SymbolTable{make([]Entry, 0, initialTableCapacity)}
+	table := &SymbolTable{addresses: make(map[string]uint, initialTableCapacity)}
+	return table
+}
+
+// newScopedSymbolTable creates an empty symbol table that also contains the entries of parent.
+// Entries added to the table do not modify parent, so that parent can be shared.
+func newScopedSymbolTable(parent *SymbolTable) *SymbolTable {
+	table := NewSymbolTable()
+	table.parent = parent
 	return table
 }
 
@@ -68,20 +86,15 @@ func (s *SymbolTable) AddEntry(symbol string, address uint) error {
 		return fmt.Errorf("could not add entry to the sybmol table: %w", err)
 	}
 
-	s.entries = append(s.entries, entry)
+	s.addresses[entry.symbol] = entry.address
 
 	return nil
 }
 
 // Contains returns true if the symbol table contains the given symbol.
 func (s *SymbolTable) Contains(symbol string) bool {
-	for _, entry := range s.entries {
-		if entry.symbol == symbol {
-			return true
-		}
-	}
-
-	return false
+	_, ok := s.addresses[symbol]
+	return ok || (s.parent != nil && s.parent.Contains(symbol))
 }
 
 var ErrSymbolNotFound = errors.New("symbol not found")
@@ -89,11 +102,42 @@ var ErrSymbolNotFound = errors.New("symbol not found")
 // GetAddress returns the address associated with the symbol.
 // It returns an error if the symbol is not found.
 func (s *SymbolTable) GetAddress(symbol string) (uint, error) {
-	for _, entry := range s.entries {
-		if entry.symbol == symbol {
-			return entry.address, nil
-		}
+	if address, ok := s.addresses[symbol]; ok {
+		return address, nil
+	}
+	if s.parent != nil {
+		return s.parent.GetAddress(symbol)
 	}
 
 	return 0, fmt.Errorf("could not get address: %w", ErrSymbolNotFound)
 }
+
+// Symbol returns the symbol of the entry.
+func (e Entry) Symbol() string {
+	return e.symbol
+}
+
+// Address returns the address of the entry.
+func (e Entry) Address() uint {
+	return e.address
+}
+
+// Entries returns the entries of the table ordered by address, then by symbol.
+func (s *SymbolTable) Entries() []Entry {
+	var entries []Entry
+	if s.parent != nil {
+		entries = s.parent.Entries()
+	}
+	for symbol, address := range s.addresses {
+		entries = append(entries, Entry{symbol: symbol, address: address})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].address != entries[j].address {
+			return entries[i].address < entries[j].address
+		}
+		return entries[i].symbol < entries[j].symbol
+	})
+
+	return entries
+}
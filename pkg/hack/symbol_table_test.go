@@ -1,6 +1,7 @@
 package hack
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -10,10 +11,9 @@ import (
 func TestSymbolTable_AddEntry_Contains_GetAddress(t *testing.T) {
 	t.Parallel()
 
-	table := NewSymbolTable()
-
 	data := []struct {
 		testCase string
+		existing []string
 		symbol   string
 		address  uint
 		err      error
@@ -32,6 +32,7 @@ func TestSymbolTable_AddEntry_Contains_GetAddress(t *testing.T) {
 		},
 		{
 			testCase: "AddEntry: symbol already exists",
+			existing: []string{"test"},
 			symbol:   "test",
 			address:  0,
 			err:      ErrSymbolAlreadyExists,
@@ -43,6 +44,14 @@ func TestSymbolTable_AddEntry_Contains_GetAddress(t *testing.T) {
 		t.Run(d.testCase, func(t *testing.T) {
 			t.Parallel()
 
+			// Each case has its own table, so that the cases do not depend on their order.
+			table := NewSymbolTable()
+			for _, symbol := range d.existing {
+				if err := table.AddEntry(symbol, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			err := table.AddEntry(d.symbol, d.address)
 
 			if d.err != nil {
@@ -68,3 +77,24 @@ func TestSymbolTable_AddEntry_Contains_GetAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestSymbolTable_Entries(t *testing.T) {
+	t.Parallel()
+
+	table := NewSymbolTable()
+	for symbol, address := range map[string]uint{"b": 16, "a": 16, "LOOP": 4} {
+		err := table.AddEntry(symbol, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for _, entry := range table.Entries() {
+		got = append(got, fmt.Sprintf("%s=%d", entry.Symbol(), entry.Address()))
+	}
+
+	if diff := cmp.Diff(got, []string{"LOOP=4", "a=16", "b=16"}); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,86 @@
+package hack
+
+import "io"
+
+// Kinds of symbols.
+const (
+	SymbolPredefined = "predefined"
+	SymbolLabel      = "label"
+	SymbolVariable   = "variable"
+)
+
+// Reference is a use of a symbol by an A-instruction of the program.
+type Reference struct {
+	Position
+
+	// Jump is true if the instruction following the A-instruction jumps,
+	// so that the symbol is the target of the jump rather than an address or a value read.
+	Jump bool
+}
+
+// CrossReference is a symbol of the symbol table of a program, with its uses.
+type CrossReference struct {
+	Symbol  string
+	Kind    string
+	Address uint
+
+	// Definition is the position of the label, or of the first A-instruction using the variable,
+	// which allocated its address. It is zero for predefined symbols.
+	Definition Position
+
+	// Library is true for the symbols of the routines of the standard library used by the program.
+	// Their definitions are positions in the sources of the routines.
+	Library bool
+
+	// References are the A-instructions of the program using the symbol, in order.
+	References []Reference
+}
+
+// CrossReferences assembles the program without writing it, and returns the labels and the variables
+// of its symbol table, and the predefined symbols it uses, ordered by address, then by symbol.
+// Symbols are named as in the symbol table, so the private symbols of files are qualified.
+// The program is not stripped.
+func (c *Config) CrossReferences(program *Program) ([]CrossReference, error) {
+	a := c.NewAssembler(nil, io.Discard)
+	r, err := a.resolve(program)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make(map[string][]Reference)
+	for address, symbol := range r.symbols[:r.programSize] {
+		if symbol == "" {
+			continue
+		}
+
+		jump := false
+		if address+1 < len(r.instructions) {
+			next, ok := r.instructions[address+1].(*CInstruction)
+			jump = ok && next.Jump != ""
+		}
+		references[symbol] = append(references[symbol], Reference{Position: r.instructions[address].Pos(), Jump: jump})
+	}
+
+	var xrefs []CrossReference
+	for _, entry := range a.symbolTable.Entries() {
+		xref := CrossReference{Symbol: entry.Symbol(), Address: entry.Address(), References: references[entry.Symbol()]}
+
+		if a.config.predefined.Contains(xref.Symbol) {
+			if len(xref.References) == 0 {
+				continue
+			}
+			xref.Kind = SymbolPredefined
+		} else {
+			definition := r.definitions[xref.Symbol]
+			xref.Kind = SymbolVariable
+			if definition.label {
+				xref.Kind = SymbolLabel
+			}
+			xref.Definition, xref.Library = definition.Position, definition.library
+		}
+
+		xrefs = append(xrefs, xref)
+	}
+
+	return xrefs, nil
+}
@@ -0,0 +1,104 @@
+package hack
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// crossReferences returns the cross-references of the source with the standard configuration.
+func crossReferences(t *testing.T, source string) []CrossReference {
+	t.Helper()
+
+	config, err := standardConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := config.Parse(strings.NewReader(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	xrefs, err := config.CrossReferences(program)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return xrefs
+}
+
+func TestConfig_CrossReferences(t *testing.T) {
+	t.Parallel()
+
+	source := "@i\n" +
+		"M=0\n" +
+		"(LOOP)\n" +
+		"@R0\n" +
+		"D=M\n" +
+		"@i\n" +
+		"D=D-M\n" +
+		"@END\n" +
+		"D;JLE\n" +
+		"@i\n" +
+		"M=M+1\n" +
+		"@LOOP\n" +
+		"0;JMP\n" +
+		"(END)\n" +
+		"@sum\n" +
+		"M=D\n" +
+		"@END\n" +
+		"0;JMP\n"
+
+	want := []CrossReference{
+		{Symbol: "R0", Kind: SymbolPredefined, Address: 0, References: []Reference{{Position: Position{Line: 4, Column: 1}}}},
+		{Symbol: "LOOP", Kind: SymbolLabel, Address: 2, Definition: Position{Line: 3, Column: 1}, References: []Reference{
+			{Position: Position{Line: 12, Column: 1}, Jump: true},
+		}},
+		{Symbol: "END", Kind: SymbolLabel, Address: 12, Definition: Position{Line: 14, Column: 1}, References: []Reference{
+			{Position: Position{Line: 8, Column: 1}, Jump: true},
+			{Position: Position{Line: 17, Column: 1}, Jump: true},
+		}},
+		{Symbol: "i", Kind: SymbolVariable, Address: 16, Definition: Position{Line: 1, Column: 1}, References: []Reference{
+			{Position: Position{Line: 1, Column: 1}},
+			{Position: Position{Line: 6, Column: 1}},
+			{Position: Position{Line: 10, Column: 1}},
+		}},
+		{Symbol: "sum", Kind: SymbolVariable, Address: 17, Definition: Position{Line: 15, Column: 1}, References: []Reference{
+			{Position: Position{Line: 15, Column: 1}},
+		}},
+	}
+
+	if diff := cmp.Diff(crossReferences(t, source), want); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfig_CrossReferences_Files(t *testing.T) {
+	t.Parallel()
+
+	xrefs := crossReferences(t, ".file Main\n(LOOP)\n@x\nM=0\n@LOOP\n0;JMP\n")
+
+	var got []string
+	for _, xref := range xrefs {
+		got = append(got, xref.Kind+" "+xref.Symbol)
+	}
+	if diff := cmp.Diff(got, []string{"label Main.LOOP", "variable Main.x"}); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestConfig_CrossReferences_Library(t *testing.T) {
+	t.Parallel()
+
+	xrefs := crossReferences(t, ".use mult\n@RET\nD=A\n@std.mult\n0;JMP\n(RET)\n@RET\n0;JMP\n")
+
+	for _, xref := range xrefs {
+		if xref.Symbol == "std.mult" {
+			if !xref.Library || xref.Kind != SymbolLabel || len(xref.References) != 1 || !xref.References[0].Jump {
+				t.Errorf("got %+v", xref)
+			}
+			return
+		}
+	}
+	t.Error("std.mult not found")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/yuxki/hack-assembler/pkg/hack"
+)
+
+// formatReferences formats the lines of the references of a symbol, reads and jumps apart.
+func formatReferences(references []hack.Reference) string {
+	var reads, jumps []string
+	for _, reference := range references {
+		line := fmt.Sprint(reference.Line)
+		if reference.Jump {
+			jumps = append(jumps, line)
+		} else {
+			reads = append(reads, line)
+		}
+	}
+
+	var parts []string
+	if len(reads) > 0 {
+		parts = append(parts, "read "+strings.Join(reads, ","))
+	}
+	if len(jumps) > 0 {
+		parts = append(parts, "jump "+strings.Join(jumps, ","))
+	}
+	if len(parts) == 0 {
+		return "unused"
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// printCrossReferences prints the cross-references of the program read from reader.
+func printCrossReferences(config assemblerConfig, reader io.Reader) error {
+	program, err := config.config.Parse(reader)
+	if err != nil {
+		return err
+	}
+
+	xrefs, err := config.config.CrossReferences(program)
+	if err != nil {
+		return err
+	}
+
+	for _, xref := range xrefs {
+		definition := "-"
+		switch {
+		case xref.Library:
+			definition = "library"
+		case xref.Kind != hack.SymbolPredefined:
+			definition = fmt.Sprintf("line %d", xref.Definition.Line)
+		}
+		fmt.Printf("%5d %-10s %-20s %-9s %s\n",
+			xref.Address, xref.Kind, xref.Symbol, definition, formatReferences(xref.References))
+	}
+
+	return nil
+}
+
+func runSymbols(args []string) int {
+	flagSet := newFlagSet("symbols", "[options] <asm file>\n"+
+		"Prints the address and the name of every label and variable, ordered by address.\n"+
+		"With -xref, also prints the kind of each symbol, its definition and the lines referring to it.")
+	all := flagSet.Bool("all", false, "also print the predefined symbols")
+	xref := flagSet.Bool("xref", false,
+		"print a cross-reference of the symbols, with the lines reading them and jumping to them")
+	options := newAssemblerFlags(flagSet)
+	if ok, code := parseFlags(flagSet, args); !ok {
+		return code
+	}
+
+	if flagSet.NArg() != 1 {
+		flagSet.Usage()
+		return exitUsage
+	}
+
+	reader, err := openInput(flagSet.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: could not open asm file: %s\n", err.Error())
+		return exitFailure
+	}
+	defer reader.Close()
+
+	config, err := options.load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		return exitFailure
+	}
+
+	if *xref {
+		err = printCrossReferences(config, reader)
+		if err != nil {
+			fprintErrors(os.Stderr, "could not assemble file: ", err)
+			return exitFailure
+		}
+		return exitOK
+	}
+
+	assembler := config.newAssembler(reader, io.Discard)
+
+	err = config.assemble(assembler)
+	printWarnings(assembler)
+	if err != nil {
+		fprintErrors(os.Stderr, "could not assemble file: ", err)
+		return exitFailure
+	}
+
+	for _, entry := range assembler.SymbolTable().Entries() {
+		if config.config.IsPredefined(entry.Symbol()) && !*all {
+			continue
+		}
+		fmt.Printf("%5d %s\n", entry.Address(), entry.Symbol())
+	}
+
+	return exitOK
+}
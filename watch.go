@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// watchInterval is how often the asm files are polled for changes.
+	watchInterval = 250 * time.Millisecond
+	// watchDebounce is how long the asm files must stay unchanged before reassembling,
+	// so that editors saving several times in a row trigger a single assembly.
+	watchDebounce = 100 * time.Millisecond
+)
+
+// fileState is what polling compares to detect changes of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+// watchState maps the watched files to their states.
+type watchState map[string]fileState
+
+func (s watchState) equal(other watchState) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for file, state := range s {
+		o, ok := other[file]
+		if !ok || o.exists != state.exists || o.size != state.size || !o.modTime.Equal(state.modTime) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// pollInputs expands the arguments into asm files and returns their states.
+// Expanding again on every poll picks up asm files added to watched directories and patterns.
+func pollInputs(args []string) ([]string, watchState) {
+	files, err := expandInputs(args)
+	if err != nil {
+		files = nil
+	}
+
+	state := make(watchState, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			state[file] = fileState{}
+			continue
+		}
+		state[file] = fileState{modTime: info.ModTime(), size: info.Size(), exists: true}
+	}
+
+	return files, state
+}
+
+// watchAsm assembles the asm files named by the arguments, then polls them and
+// assembles them again whenever they change, until interrupted.
+func watchAsm(args []string, assemble func(asmFiles []string)) int {
+	for _, arg := range args {
+		if arg == stdio {
+			fmt.Fprintln(os.Stderr, "Error: the standard input can not be watched")
+			return exitUsage
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files, state := pollInputs(args)
+	for {
+		fmt.Printf("[%s] assembling\n", time.Now().Format(time.TimeOnly))
+		assemble(files)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return exitOK
+			case <-time.After(watchInterval):
+			}
+
+			_, next := pollInputs(args)
+			if next.equal(state) {
+				continue
+			}
+
+			// Wait until the files stop changing.
+			for !next.equal(state) {
+				state = next
+				select {
+				case <-ctx.Done():
+					return exitOK
+				case <-time.After(watchDebounce):
+				}
+				files, next = pollInputs(args)
+			}
+			break
+		}
+	}
+}